@@ -0,0 +1,140 @@
+package flatlang
+
+import "github.com/lithdew/flatlang/ast"
+
+// newAST converts the program node n into a typed syntax tree.
+func newAST(lx *Lexer, n *Node) *ast.Program {
+	c := converter{lx: lx}
+	return c.program(n)
+}
+
+type converter struct {
+	lx *Lexer
+}
+
+func (c converter) span(n *Node) ast.Span {
+	pos, end := n.Span(c.lx)
+	return ast.Span{From: pos, To: end}
+}
+
+func (c converter) pos(tok int) int { return c.lx.Tokens[tok].Pos }
+
+func (c converter) program(n *Node) *ast.Program {
+	prog := &ast.Program{
+		Span:       ast.Span{From: 0, To: len(c.lx.Data)},
+		Stmts:      make([]ast.Stmt, 0, len(n.Nodes)),
+		Semicolons: make([]int, 0, len(n.Tokens)),
+	}
+	for _, child := range n.Nodes {
+		switch child.Type {
+		case VarNode:
+			prog.Stmts = append(prog.Stmts, c.assign(child))
+		case ValNode:
+			prog.Stmts = append(prog.Stmts, c.pipeline(child.Nodes, child.Tokens))
+		}
+	}
+	for _, tok := range n.Tokens {
+		prog.Semicolons = append(prog.Semicolons, c.pos(tok))
+	}
+	return prog
+}
+
+func (c converter) assign(n *Node) *ast.Assign {
+	return &ast.Assign{
+		Span:   c.span(n),
+		Name:   c.ident(n.Nodes[0]),
+		Assign: c.pos(n.Tokens[0]),
+		Value:  c.pipeline(n.Nodes[1:], n.Tokens[1:]),
+	}
+}
+
+func (c converter) pipeline(stages []*Node, pipes []int) *ast.Pipeline {
+	p := &ast.Pipeline{
+		Calls: make([]*ast.Call, 0, len(stages)),
+		Pipes: make([]int, 0, len(pipes)),
+	}
+	for _, stage := range stages {
+		p.Calls = append(p.Calls, c.call(stage))
+	}
+	for _, tok := range pipes {
+		p.Pipes = append(p.Pipes, c.pos(tok))
+	}
+	p.Span = ast.Span{From: p.Calls[0].Pos(), To: p.Calls[len(p.Calls)-1].End()}
+	return p
+}
+
+func (c converter) call(n *Node) *ast.Call {
+	call := &ast.Call{Span: c.span(n), Fun: c.expr(n.Nodes[0])}
+	for _, arg := range n.Nodes[1:] {
+		call.Args = append(call.Args, c.expr(arg))
+	}
+	return call
+}
+
+func (c converter) ident(n *Node) *ast.Ident {
+	return &ast.Ident{Span: c.span(n), Name: n.Val(c.lx)}
+}
+
+func (c converter) expr(n *Node) ast.Expr {
+	switch n.Type {
+	case IdentNode:
+		return c.ident(n)
+	case IntNode:
+		return &ast.BasicLit{Span: c.span(n), Kind: ast.Int, Value: n.Val(c.lx)}
+	case FloatNode:
+		return &ast.BasicLit{Span: c.span(n), Kind: ast.Float, Value: n.Val(c.lx)}
+	case BoolNode:
+		return &ast.BasicLit{Span: c.span(n), Kind: ast.Bool, Value: n.Val(c.lx)}
+	case StringNode:
+		lit := &ast.StringLit{Span: c.span(n), Quote: c.lx.Data[c.pos(n.Tokens[0])]}
+		for _, part := range n.Nodes {
+			switch part.Type {
+			case TextNode:
+				lit.Parts = append(lit.Parts, &ast.Text{Span: c.span(part), Value: part.Val(c.lx)})
+			case InterpNode:
+				lit.Parts = append(lit.Parts, &ast.Interp{Span: c.span(part), X: c.expr(part.Nodes[0])})
+			}
+		}
+		return lit
+	case ListNode:
+		lit := &ast.ListLit{Span: c.span(n)}
+		for _, elem := range n.Nodes {
+			lit.Elems = append(lit.Elems, c.expr(elem))
+		}
+		for _, tok := range n.Tokens[1 : len(n.Tokens)-1] {
+			lit.Commas = append(lit.Commas, c.pos(tok))
+		}
+		return lit
+	case MapNode:
+		// The tokens of a map are its opening brace, followed by the colon of each field separated by commas,
+		// followed by its closing brace.
+
+		lit := &ast.MapLit{Span: c.span(n)}
+		for i := 0; i < len(n.Nodes); i += 2 {
+			key, val := c.ident(n.Nodes[i]), c.expr(n.Nodes[i+1])
+			lit.Fields = append(lit.Fields, &ast.Field{
+				Span:  ast.Span{From: key.Pos(), To: val.End()},
+				Key:   key,
+				Colon: c.pos(n.Tokens[1+i]),
+				Value: val,
+			})
+			if i > 0 {
+				lit.Commas = append(lit.Commas, c.pos(n.Tokens[i]))
+			}
+		}
+		return lit
+	case ExprNode:
+		return &ast.ParenExpr{Span: c.span(n), X: c.expr(n.Nodes[0])}
+	}
+
+	if len(n.Nodes) == 1 {
+		return &ast.UnaryExpr{Span: c.span(n), Op: n.Type.String(), OpPos: c.pos(n.Tokens[0]), X: c.expr(n.Nodes[0])}
+	}
+	return &ast.BinaryExpr{
+		Span:  c.span(n),
+		X:     c.expr(n.Nodes[0]),
+		Op:    n.Type.String(),
+		OpPos: c.pos(n.Tokens[0]),
+		Y:     c.expr(n.Nodes[1]),
+	}
+}
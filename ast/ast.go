@@ -0,0 +1,181 @@
+// Package ast declares the types used to represent the syntax tree of a flatlang program.
+//
+// Every node records the byte offsets in the source of its first character, and of the character immediately after
+// it.
+package ast
+
+// Node is a node in the syntax tree.
+type Node interface {
+	Pos() int // offset of the first byte of the node
+	End() int // offset of the byte immediately after the node
+}
+
+// Stmt is a statement. A statement is either an assignment, or a pipeline.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Expr is an expression.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// StringPart is a part of a string literal. A string part is either text, or an interpolated expression.
+type StringPart interface {
+	Node
+	stringPartNode()
+}
+
+// Span is the range of bytes [From, To) covered by a node.
+type Span struct {
+	From, To int
+}
+
+func (s Span) Pos() int { return s.From }
+func (s Span) End() int { return s.To }
+
+// Program is a sequence of statements, each terminated by a semicolon.
+type Program struct {
+	Span
+	Stmts      []Stmt
+	Semicolons []int // offsets of the ';' terminating each statement
+}
+
+// Assign assigns a pipeline to a variable.
+type Assign struct {
+	Span
+	Name   *Ident
+	Assign int // offset of '='
+	Value  *Pipeline
+}
+
+// Pipeline is a chain of calls linked together using pipe syntax ('>').
+type Pipeline struct {
+	Span
+	Calls []*Call
+	Pipes []int // offsets of each '>' linking two calls
+}
+
+// Call is a stage of a pipeline. Fun is the method called, and Args are the params it is called with. A call with no
+// args may just be an expression, like a literal or an identifier referring to a variable.
+type Call struct {
+	Span
+	Fun  Expr
+	Args []Expr
+}
+
+// Ident is an identifier.
+type Ident struct {
+	Span
+	Name string
+}
+
+// LitKind is the kind of a basic literal.
+type LitKind int
+
+const (
+	Int LitKind = iota
+	Float
+	Bool
+)
+
+func (k LitKind) String() string {
+	switch k {
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case Bool:
+		return "bool"
+	}
+	return ""
+}
+
+// BasicLit is an int, float or bool literal.
+type BasicLit struct {
+	Span
+	Kind  LitKind
+	Value string // literal as written in the source
+}
+
+// StringLit is a string literal quoted using either ', " or `. Only strings quoted using ` may hold interpolated
+// expressions.
+type StringLit struct {
+	Span
+	Quote byte
+	Parts []StringPart
+}
+
+// Text is text in a string literal, as written in the source.
+type Text struct {
+	Span
+	Value string
+}
+
+// Interp is an expression interpolated into a string literal using ${}.
+type Interp struct {
+	Span
+	X Expr
+}
+
+// ListLit is a list literal.
+type ListLit struct {
+	Span
+	Elems  []Expr
+	Commas []int // offsets of each ',' separating two elements
+}
+
+// MapLit is a map literal.
+type MapLit struct {
+	Span
+	Fields []*Field
+	Commas []int // offsets of each ',' separating two fields
+}
+
+// Field is a field of a map literal.
+type Field struct {
+	Span
+	Key   *Ident
+	Colon int // offset of ':'
+	Value Expr
+}
+
+// ParenExpr is a parenthesized expression.
+type ParenExpr struct {
+	Span
+	X Expr
+}
+
+// UnaryExpr is an expression with a prefix operator. Op is one of -, !, <, >, <= or >=.
+type UnaryExpr struct {
+	Span
+	Op    string
+	OpPos int
+	X     Expr
+}
+
+// BinaryExpr is an expression with an infix operator. Op is one of +, -, *, /, & or |.
+type BinaryExpr struct {
+	Span
+	X     Expr
+	Op    string
+	OpPos int
+	Y     Expr
+}
+
+func (*Assign) stmtNode()   {}
+func (*Pipeline) stmtNode() {}
+
+func (*Ident) exprNode()      {}
+func (*BasicLit) exprNode()   {}
+func (*StringLit) exprNode()  {}
+func (*ListLit) exprNode()    {}
+func (*MapLit) exprNode()     {}
+func (*ParenExpr) exprNode()  {}
+func (*UnaryExpr) exprNode()  {}
+func (*BinaryExpr) exprNode() {}
+
+func (*Text) stringPartNode()   {}
+func (*Interp) stringPartNode() {}
@@ -0,0 +1,52 @@
+package ast
+
+// Inspect traverses the syntax tree rooted at node in depth-first order. It calls fn(node) for each node, and stops
+// traversing the children of a node should fn return false.
+func Inspect(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, stmt := range n.Stmts {
+			Inspect(stmt, fn)
+		}
+	case *Assign:
+		Inspect(n.Name, fn)
+		Inspect(n.Value, fn)
+	case *Pipeline:
+		for _, call := range n.Calls {
+			Inspect(call, fn)
+		}
+	case *Call:
+		Inspect(n.Fun, fn)
+		for _, arg := range n.Args {
+			Inspect(arg, fn)
+		}
+	case *StringLit:
+		for _, part := range n.Parts {
+			Inspect(part, fn)
+		}
+	case *Interp:
+		Inspect(n.X, fn)
+	case *ListLit:
+		for _, elem := range n.Elems {
+			Inspect(elem, fn)
+		}
+	case *MapLit:
+		for _, field := range n.Fields {
+			Inspect(field, fn)
+		}
+	case *Field:
+		Inspect(n.Key, fn)
+		Inspect(n.Value, fn)
+	case *ParenExpr:
+		Inspect(n.X, fn)
+	case *UnaryExpr:
+		Inspect(n.X, fn)
+	case *BinaryExpr:
+		Inspect(n.X, fn)
+		Inspect(n.Y, fn)
+	}
+}
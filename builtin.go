@@ -0,0 +1,183 @@
+package flatlang
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+)
+
+// Builtin is a method that may be called from flatlang programs. Builtins that are hand-written against Builtin are
+// called directly, whereas Go funcs registered as builtins are called through reflection by FuncBuiltin.
+type Builtin interface {
+	// Type returns the Go func type that describes the builtin. The builtin is handed the context it is called in
+	// should the first param of the func type be a context.Context, and the pipe of the pipeline it is called from
+	// should the next param be a *Pipe. The value returned by Call replaces the value held by the pipe should the
+	// func type return a value besides an error. The number of args that Call is called with is checked against the
+	// params of the func type beforehand.
+	Type() reflect.Type
+
+	// Call calls the builtin with args. Builtins report args that are not of the types they expect by returning an
+	// *ArgTypeError. Any other error returned is wrapped into a *BuiltinError.
+	Call(ctx *CallContext, args []interface{}) (interface{}, error)
+}
+
+// CallContext holds the context that a builtin is called in.
+type CallContext struct {
+	Context context.Context // context of the evaluation that the builtin is called from
+	Name    string          // name that the builtin is registered under
+	Pipe    *Pipe           // pipe of the pipeline that the builtin is called from, or nil
+}
+
+// PanicError is reported should a builtin panic while it is called.
+type PanicError struct {
+	Builtin string      // name of the builtin
+	Value   interface{} // value that the builtin panicked with
+	Stack   []byte      // stack trace of the goroutine that the builtin panicked in
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Builtin, e.Value)
+}
+
+// callBuiltin calls b, recovering any panic raised by b into a *PanicError. Panics are detected by b not returning
+// rather than by the value recovered, as panicking with nil recovers nil.
+func callBuiltin(b Builtin, ctx *CallContext, args []interface{}) (res interface{}, err error) {
+	completed := false
+	defer func() {
+		if v := recover(); !completed {
+			res, err = nil, &PanicError{Builtin: ctx.Name, Value: v, Stack: debug.Stack()}
+		}
+	}()
+	res, err = b.Call(ctx, args)
+	completed = true
+	return res, err
+}
+
+// checkBuiltinType checks that t is a func type that may describe a builtin.
+func checkBuiltinType(t reflect.Type) error {
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("%v is not a func", t)
+	}
+	if t.NumOut() > 2 {
+		return fmt.Errorf("methods may only return a value and an error at most")
+	}
+	if t.NumOut() == 2 && !t.Out(1).Implements(errType) {
+		return fmt.Errorf("second return val of method is expected to be an error, but got %v", t.Out(1))
+	}
+	return nil
+}
+
+// returnsValue reports whether a builtin of the func type t returns a value.
+func returnsValue(t reflect.Type) bool {
+	return t.NumOut() == 2 || (t.NumOut() == 1 && !t.Out(0).Implements(errType))
+}
+
+// takesContext reports whether a builtin of the func type t is handed the context it is called in.
+func takesContext(t reflect.Type) bool {
+	return t.NumIn() > 0 && t.In(0) == contextType
+}
+
+// takesPipe reports whether a builtin of the func type t is handed the pipe of the pipeline it is called from.
+func takesPipe(t reflect.Type) bool {
+	i := 0
+	if takesContext(t) {
+		i = 1
+	}
+	return t.NumIn() > i && t.In(i) == pipeType
+}
+
+// checkArity checks that a builtin name of the func type t may be called with n args. The context and the pipe that
+// the builtin may be handed are not counted as args.
+func checkArity(name string, t reflect.Type, n int) error {
+	offset := implicitParams(t)
+	if t.IsVariadic() {
+		if n < t.NumIn()-offset-1 {
+			return &ArityError{Builtin: name, Want: t.NumIn() - offset - 1, Variadic: true, Got: n}
+		}
+		return nil
+	}
+	if n != t.NumIn()-offset {
+		return &ArityError{Builtin: name, Want: t.NumIn() - offset, Got: n}
+	}
+	return nil
+}
+
+// paramType returns the type of the param of the func type t that arg i of a builtin of the type is passed to.
+func paramType(t reflect.Type, i int) reflect.Type {
+	i += implicitParams(t)
+	if t.IsVariadic() && i >= t.NumIn()-1 {
+		return t.In(t.NumIn() - 1).Elem()
+	}
+	return t.In(i)
+}
+
+// implicitParams returns the number of leading params of the func type t that are not passed args, being the
+// context and the pipe that a builtin may be handed.
+func implicitParams(t reflect.Type) int {
+	n := 0
+	if takesContext(t) {
+		n++
+	}
+	if takesPipe(t) {
+		n++
+	}
+	return n
+}
+
+type funcBuiltin struct {
+	fn   reflect.Value
+	typ  reflect.Type
+	ctx  bool
+	pipe bool
+}
+
+// FuncBuiltin returns a Builtin that calls the Go func fn through reflection. Args are converted into the types of
+// the params of fn should they not be assignable to them: ints are narrowed with range checks, lists are converted
+// element-wise, and maps are decoded into structs.
+func FuncBuiltin(fn interface{}) (Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%T is not a func", fn)
+	}
+	if err := checkBuiltinType(v.Type()); err != nil {
+		return nil, err
+	}
+	return funcBuiltin{fn: v, typ: v.Type(), ctx: takesContext(v.Type()), pipe: takesPipe(v.Type())}, nil
+}
+
+func (b funcBuiltin) Type() reflect.Type { return b.typ }
+
+func (b funcBuiltin) Call(ctx *CallContext, args []interface{}) (interface{}, error) {
+	pvs := make([]reflect.Value, 0, len(args)+2)
+
+	if b.ctx {
+		pvs = append(pvs, reflect.ValueOf(&ctx.Context).Elem())
+	}
+	if b.pipe {
+		pvs = append(pvs, reflect.ValueOf(ctx.Pipe))
+	}
+
+	for i, arg := range args {
+		it := paramType(b.typ, i)
+		pv, err := convert(arg, it)
+		if err != nil {
+			return nil, &ArgTypeError{Builtin: ctx.Name, Index: i, Type: it, Value: arg, Err: err}
+		}
+		pvs = append(pvs, pv)
+	}
+
+	out := b.fn.Call(pvs)
+
+	if len(out) > 0 && out[len(out)-1].Type().Implements(errType) {
+		if err := out[len(out)-1]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+		out = out[:len(out)-1]
+	}
+
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out[0].Interface(), nil
+}
@@ -0,0 +1,123 @@
+package flatlang
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"testing"
+)
+
+type testAddBuiltin struct{}
+
+var testAddType = reflect.TypeOf((func(*Pipe, int64) int64)(nil))
+
+func (testAddBuiltin) Type() reflect.Type { return testAddType }
+
+func (testAddBuiltin) Call(ctx *CallContext, args []interface{}) (interface{}, error) {
+	x, ok := args[0].(int64)
+	if !ok {
+		return nil, fmt.Errorf("%s: arg 0: expected an int, got %s", ctx.Name, typeName(args[0]))
+	}
+	y, _ := ctx.Pipe.Value.(int64)
+	return x + y, nil
+}
+
+type testInvalidBuiltin struct{}
+
+func (testInvalidBuiltin) Type() reflect.Type { return reflect.TypeOf(0) }
+
+func (testInvalidBuiltin) Call(*CallContext, []interface{}) (interface{}, error) { return nil, nil }
+
+func TestBuiltin(t *testing.T) {
+	ex := NewEval(nil)
+	require.NoError(t, ex.RegisterBuiltin("add", testAddBuiltin{}))
+
+	typ, exists := ex.BuiltinType("add")
+	require.True(t, exists)
+	require.Equal(t, testAddType, typ)
+
+	res, err := ex.Run(Pipeline{{name: "add", params: []interface{}{int64(1)}}, {name: "add", params: []interface{}{int64(2)}}}, int64(3))
+	require.NoError(t, err)
+	require.Equal(t, int64(6), res)
+
+	_, err = ex.dispatch("add", &Pipe{}, "x")
+	require.EqualError(t, err, "add: arg 0: expected an int, got string")
+
+	_, err = ex.dispatch("add", &Pipe{}, int64(1), int64(2))
+	require.EqualError(t, err, "add: expected exactly 1 param(s), got 2 param(s)")
+
+	require.EqualError(t, ex.RegisterBuiltin("invalid", testInvalidBuiltin{}), "\"invalid\": int is not a func")
+
+	_, err = FuncBuiltin(1)
+	require.EqualError(t, err, "int is not a func")
+
+	_, err = FuncBuiltin(func() (int, int) { return 0, 0 })
+	require.EqualError(t, err, "second return val of method is expected to be an error, but got int")
+}
+
+func TestBuiltinPanic(t *testing.T) {
+	lx, err := Lex([]byte("x = index [1, 2] 2;"), "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	ex := NewEval(lx)
+	require.NoError(t, ex.RegisterBuiltin("index", func(list []interface{}, i int) interface{} {
+		return list[i]
+	}))
+
+	_, err = ex.Eval(px.Result)
+
+	var pe *PanicError
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, "index", pe.Builtin)
+	require.Contains(t, pe.Value.(error).Error(), "index out of range [2] with length 2")
+	require.Contains(t, string(pe.Stack), "TestBuiltinPanic")
+	require.EqualError(t, err, "(input):1:5: failed to call method \"index\": index panicked: runtime error: index out of range [2] with length 2")
+
+	// Panicking with nil is reported as well, despite nil being recovered.
+
+	lx, err = Lex([]byte("x = fail;"), "")
+	require.NoError(t, err)
+	px, err = Parse(lx)
+	require.NoError(t, err)
+
+	ex.SetLexer(lx)
+	require.NoError(t, ex.RegisterBuiltin("fail", func() int { panic(nil) }))
+
+	_, err = ex.Eval(px.Result)
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, "fail", pe.Builtin)
+}
+
+func BenchmarkDispatch(b *testing.B) {
+	fn := func(p *Pipe, x int64) int64 {
+		y, _ := p.Value.(int64)
+		return x + y
+	}
+
+	builtins := map[string]interface{}{
+		"func":    fn,
+		"builtin": testAddBuiltin{},
+	}
+
+	for _, name := range []string{"func", "builtin"} {
+		b.Run(name, func(b *testing.B) {
+			ex := NewEval(nil)
+			require.NoError(b, ex.RegisterBuiltin(name, builtins[name]))
+
+			pipe := &Pipe{Value: int64(0)}
+			params := []interface{}{int64(1)}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := ex.dispatch(name, pipe, params...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
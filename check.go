@@ -0,0 +1,317 @@
+package flatlang
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// Check checks n, which is parsed from the tokens of lx, against the builtins fns without evaluating n. See
+// (*Evaluator).Check.
+func Check(lx *Lexer, n *Node, fns map[string]interface{}) error {
+	e := NewEval(lx)
+	if err := e.RegisterBuiltins(fns); err != nil {
+		return err
+	}
+	return e.Check(n)
+}
+
+// Check reports the problems that evaluating n would run into without evaluating n, such that programs may be
+// validated before they are run. Identifiers are resolved against the variables assigned by n beforehand, the
+// variables of e, the builtins registered to e, and Types. Each builtin called by n is checked to be called with as
+// many args as it takes, and args whose values are known without calling any builtin are checked to be convertible
+// into the types of the params they are passed to. Pipelines assigned to variables that n does not run are checked as
+// though they were run as is.
+//
+// Literals, and expressions composed of literals, are evaluated such that malformed literals and failed arithmetic
+// are reported as well. Any error returned is a list of Diagnostics sorted by position, holding every problem found.
+func (e *Evaluator) Check(n *Node) error {
+	return e.CheckContext(context.Background(), n)
+}
+
+// CheckContext checks n the same way that Check does. Expressions whose values are known stop being evaluated once
+// ctx is done, or once e.Limits are exceeded, with the error that stopped them being reported.
+func (e *Evaluator) CheckContext(ctx context.Context, n *Node) error {
+	c := &checker{
+		ctx:   ctx,
+		e:     e,
+		vars:  make(map[string]interface{}, len(e.sym)),
+		used:  make(map[string]bool),
+		loose: make(map[*Node]bool),
+		seen:  make(map[diagnosticKey]bool),
+	}
+	for name, val := range e.sym {
+		c.vars[name] = val
+	}
+
+	// Expressions whose values are known are evaluated by an evaluator without builtins, which only looks up the
+	// variables whose values are known. Values are bound by the limits of e the same way as when they are evaluated.
+
+	c.known = &Evaluator{
+		Decimals:  e.Decimals,
+		NonFinite: e.NonFinite,
+		Limits:    e.Limits,
+		lx:        e.lx,
+		sym:       c.vars,
+		builtins:  make(map[string]Builtin),
+	}
+
+	c.check(n)
+
+	if len(c.diags) == 0 {
+		return nil
+	}
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		return c.diags[i].Pos.Offset < c.diags[j].Pos.Offset
+	})
+	return c.diags
+}
+
+// unknownValue stands in for values that are only known once a program is evaluated, such as values returned by
+// builtins.
+type unknownValue struct{}
+
+// invalidValue stands in for values of nodes that a problem was reported for. It may stand in for any number of
+// values or method calls, such that the args of a method call that it is passed to are not checked.
+type invalidValue struct{}
+
+type diagnosticKey struct {
+	pos, end int
+	msg      string
+}
+
+type checker struct {
+	ctx   context.Context
+	e     *Evaluator
+	known *Evaluator             // evaluates expressions whose values are known
+	vars  map[string]interface{} // values of variables, which are unknownValue should they only be known once evaluated
+	defs  []string               // variables assigned pipelines by the program, in order
+	used  map[string]bool        // variables whose pipelines are made part of another pipeline
+	loose map[*Node]bool         // nodes of methods whose args are not checked, as they are passed invalid values
+	diags Diagnostics
+	seen  map[diagnosticKey]bool
+}
+
+// report reports err, which is caused by nodes parsed from the tokens of lx. Problems that are found more than once,
+// such as those within a pipeline that is run by several statements, are only reported once.
+func (c *checker) report(lx *Lexer, err error, nodes ...*Node) {
+	diag := Diagnostic{Severity: SeverityError, Message: err.Error()}
+	var ee *EvalError
+	if errors.As(errorAt(lx, err, nodes...), &ee) {
+		diag.Pos, diag.End, diag.Message = ee.Pos, ee.End, ee.Err.Error()
+	}
+	key := diagnosticKey{pos: diag.Pos.Offset, end: diag.End.Offset, msg: diag.Message}
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	c.diags = append(c.diags, diag)
+}
+
+// check checks n the same way that n is evaluated. It returns the value of n should it be known, the method call or
+// pipeline that n evaluates to, unknownValue, or invalidValue should a problem have been reported for n.
+func (c *checker) check(n *Node) interface{} {
+	switch n.Type {
+	case ProgramNode:
+		order, err := c.e.order(n)
+		if err != nil {
+			c.report(c.e.lx, err)
+			return nil
+		}
+		for _, i := range order {
+			switch res := c.check(n.Nodes[i]).(type) {
+			case methodCall:
+				c.run(Pipeline{res})
+			case Pipeline:
+				c.run(res)
+			}
+		}
+		for _, sym := range c.defs {
+			if p, ok := c.vars[sym].(Pipeline); ok && !c.used[sym] {
+				c.run(p)
+			}
+		}
+		return nil
+	case VarNode:
+		sym := n.Nodes[0].Val(c.e.lx)
+		rhs := n.Nodes[1:]
+
+		var val interface{}
+		if len(rhs) == 1 {
+			val = c.check(rhs[0])
+			if mc, ok := val.(methodCall); ok {
+				val = Pipeline{mc}
+			}
+			if p, ok := val.(Pipeline); ok && len(p) == 1 && c.e.returnsValue(p[0].name) {
+				c.run(p)
+				val = unknownValue{}
+			}
+		} else {
+			calls := make(Pipeline, 0, len(rhs))
+			for _, node := range rhs {
+				switch res := c.check(node).(type) {
+				case methodCall:
+					calls = append(calls, res)
+				case Pipeline:
+					calls = append(calls, res...)
+				}
+			}
+			val = calls
+		}
+
+		if _, ok := val.(Pipeline); ok {
+			c.defs = append(c.defs, sym)
+			delete(c.used, sym)
+		}
+		c.vars[sym] = val
+		return nil
+	}
+
+	if c.isKnown(n) {
+		val, err := c.known.EvalContext(c.ctx, n)
+		if err != nil {
+			c.report(c.e.lx, err, n)
+			return unknownValue{}
+		}
+		return val
+	}
+
+	switch n.Type {
+	case IdentNode:
+		sym := n.Val(c.e.lx)
+		if val, recorded := c.vars[sym]; recorded {
+			if _, ok := val.(Pipeline); ok {
+				c.used[sym] = true
+			}
+			return val
+		}
+		if _, exists := c.e.builtins[sym]; exists {
+			return methodCall{name: sym, lx: c.e.lx, nodes: []*Node{n}}
+		}
+		c.report(c.e.lx, &UnknownSymbolError{Name: sym}, n)
+		return invalidValue{}
+	case ValNode:
+		if len(n.Nodes) == 1 {
+			return c.check(n.Nodes[0])
+		}
+
+		results := make(Pipeline, 0, len(n.Nodes))
+		invalid := false
+		for _, node := range n.Nodes {
+			switch res := c.check(node).(type) {
+			case methodCall:
+				results = append(results, res)
+			case Pipeline:
+				results = append(results, res...)
+			case invalidValue:
+				if len(results) > 0 && len(results[len(results)-1].nodes) > 0 {
+					c.loose[results[len(results)-1].nodes[0]] = true
+				}
+				invalid = true
+			default:
+				if len(results) == 0 {
+					if _, ok := res.(unknownValue); !ok && !invalid {
+						c.report(c.e.lx, errStrayValue, node)
+					}
+					continue
+				}
+				last := &results[len(results)-1]
+				last.params = append(last.params[:len(last.params):len(last.params)], res)
+				last.nodes = append(last.nodes[:len(last.nodes):len(last.nodes)], node)
+			}
+		}
+		return results
+	case ExprNode:
+		return c.check(n.Nodes[0])
+	case ListNode:
+		for _, node := range n.Nodes {
+			c.call(c.check(node))
+		}
+	case MapNode:
+		for i := 1; i < len(n.Nodes); i += 2 {
+			c.call(c.check(n.Nodes[i]))
+		}
+	case InterpNode:
+		c.call(c.check(n.Nodes[0]))
+	default:
+		for _, node := range n.Nodes {
+			c.call(c.check(node))
+		}
+	}
+	return unknownValue{}
+}
+
+// isKnown reports whether the value of n is known without calling any builtin.
+func (c *checker) isKnown(n *Node) bool {
+	switch n.Type {
+	case IdentNode:
+		sym := n.Val(c.e.lx)
+		if val, recorded := c.vars[sym]; recorded {
+			switch val.(type) {
+			case unknownValue, invalidValue, methodCall, Pipeline:
+				return false
+			}
+			return true
+		}
+		if _, exists := c.e.builtins[sym]; exists {
+			return false
+		}
+		_, exists := Types[sym]
+		return exists
+	case MapNode:
+		for i := 1; i < len(n.Nodes); i += 2 {
+			if !c.isKnown(n.Nodes[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, node := range n.Nodes {
+		if !c.isKnown(node) {
+			return false
+		}
+	}
+	return true
+}
+
+// call checks val should it be a method that is called for the value it returns.
+func (c *checker) call(val interface{}) {
+	if mc, ok := val.(methodCall); ok && c.e.returnsValue(mc.name) {
+		c.run(Pipeline{mc})
+	}
+}
+
+// run checks each method call in pipeline p the same way that each call is checked before the builtin is called.
+func (c *checker) run(p Pipeline) {
+	for _, mc := range p {
+		b, exists := c.e.builtins[mc.name]
+		if !exists {
+			c.report(mc.lx, &UnknownSymbolError{Name: mc.name}, mc.nodes...)
+			continue
+		}
+
+		if len(mc.nodes) > 0 && c.loose[mc.nodes[0]] {
+			continue
+		}
+
+		t := b.Type()
+		if err := checkArity(mc.name, t, len(mc.params)); err != nil {
+			c.report(mc.lx, err, mc.nodes...)
+			continue
+		}
+
+		for i, param := range mc.params {
+			if _, ok := param.(unknownValue); ok {
+				continue
+			}
+			it := paramType(t, i)
+			if _, err := convert(param, it); err != nil {
+				nodes := mc.nodes
+				if i+1 < len(mc.nodes) {
+					nodes = mc.nodes[i+1 : i+2]
+				}
+				c.report(mc.lx, &ArgTypeError{Builtin: mc.name, Index: i, Type: it, Value: param, Err: err}, nodes...)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package flatlang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	src := `port = 8080;
+items 'start' > hello > items 'end';
+hello = items 'test' > items 'test_two';
+add 1 2;
+x = add 'one';
+y = nope + 1;
+z = [two, 'x' + 1];
+w = 1 / 0;
+unused = add;
+curried = add;
+v = curried 3;
+s = 'port: {port} {two}';
+1 items;
+items {limit: >=0 & <=port};
+add 1 missing 2;
+`
+
+	lx, err := Lex([]byte(src), "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	fail := func() { t.Fatal("builtins should not be called while checking a program") }
+
+	err = Check(lx, px.Result, map[string]interface{}{
+		"items": func(items ...string) { fail() },
+		"add":   func(p *Pipe, x int64) { fail() },
+		"two":   func() int { fail(); return 2 },
+	})
+
+	var diags Diagnostics
+	require.True(t, errors.As(err, &diags))
+	require.Equal(t, []string{
+		"(input):4:1: add: expected exactly 1 param(s), got 2 param(s)",
+		"(input):5:9: add: arg 0: cannot convert string \"one\" to int64",
+		"(input):6:5: unknown symbol 'nope'",
+		"(input):7:11: cannot eval 'x' + '1'",
+		"(input):8:5: division by zero: cannot eval '1' / '0'",
+		"(input):9:10: add: expected exactly 1 param(s), got 0 param(s)",
+		"(input):13:1: multiple values may not exist in a single statement unless they serve as parameters for a a method call",
+		"(input):14:7: items: arg 0: cannot convert map {limit: >=0 & <=8080} to string",
+		"(input):15:7: unknown symbol 'missing'",
+	}, diagnosticStrings(diags))
+}
+
+func TestCheckEvaluator(t *testing.T) {
+	ex := NewEval(nil)
+	require.NoError(t, ex.RegisterBuiltin("greet", func(name string) {}))
+
+	lx, err := Lex([]byte("greet 'A' > say_hi; say_hi = greet;"), "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	// Variables may be declared after the statements that refer to them, the same way as when they are evaluated.
+
+	ex.SetLexer(lx)
+	require.EqualError(t, ex.Check(px.Result), "(input):1:30: greet: expected exactly 1 param(s), got 0 param(s)")
+
+	// Variables assigned beforehand, such as those of an interactive session, are known to the checker.
+
+	lx, err = Lex([]byte("greet 'A' > say_hi 'B';"), "")
+	require.NoError(t, err)
+	px, err = Parse(lx)
+	require.NoError(t, err)
+
+	ex.SetLexer(lx)
+	require.EqualError(t, ex.Check(px.Result), "(input):1:13: unknown symbol 'say_hi'")
+
+	ex.sym["say_hi"] = Pipeline{{name: "greet"}}
+	require.NoError(t, ex.Check(px.Result))
+}
+
+func TestCheckLimits(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("x0 = 'x';\n")
+	for i := 1; i <= 26; i++ {
+		fmt.Fprintf(&b, "x%d = x%d + x%d;\n", i, i-1, i-1)
+	}
+
+	lx, err := Lex([]byte(b.String()), "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	// Values whose lengths exceed the limits of the evaluator are reported rather than built while checking.
+
+	ex := NewEval(lx)
+	ex.Limits.MaxStringLen = 1 << 20
+
+	var diags Diagnostics
+	require.True(t, errors.As(ex.Check(px.Result), &diags))
+	require.Equal(t, []string{
+		"(input):22:7: exceeded the maximum size of a value: string of length 2097152 exceeds 1048576",
+	}, diagnosticStrings(diags))
+
+	// Values stop being evaluated once the context that the program is checked in is done.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ex.Limits = Limits{}
+	require.True(t, errors.As(ex.CheckContext(ctx, px.Result), &diags))
+	require.Equal(t, "(input):1:6: evaluation stopped: context canceled", diagnosticStrings(diags)[0])
+}
@@ -1,12 +1,20 @@
+// Command eval is an interactive session for evaluating flatlang programs.
+//
+// Variables assigned in the session are kept across inputs. An input is evaluated once it ends with ';', or once an
+// empty line is entered. Commands that start with ':' may be entered in place of an input; type :help to list them.
 package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/chzyer/readline"
 	"github.com/lithdew/flatlang"
 	"io"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -18,62 +26,223 @@ func check(err error) {
 
 func wrap(fn func() error) { check(fn()) }
 
+var history = flag.String("history", defaultHistoryFile(), "file to persist input history to (empty to disable)")
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".flat_history")
+}
+
 func main() {
-	l, err := readline.NewEx(&readline.Config{Prompt: ">> "})
+	flag.Parse()
+
+	s := newSession(os.Stdout)
+
+	l, err := readline.NewEx(&readline.Config{
+		Prompt:       ">> ",
+		HistoryFile:  *history,
+		AutoComplete: completer{s: s},
+	})
 	check(err)
 	defer wrap(l.Close)
 
 	log.SetOutput(l.Stderr())
+	log.SetFlags(0)
+
+	var buf strings.Builder
 
 	for {
+		if buf.Len() == 0 {
+			l.SetPrompt(">> ")
+		} else {
+			l.SetPrompt(".. ")
+		}
+
 		line, err := l.Readline()
 		if err != nil {
-			if errors.Is(err, readline.ErrInterrupt) {
-				break
+			if errors.Is(err, readline.ErrInterrupt) && buf.Len() > 0 {
+				buf.Reset()
+				continue
 			}
-			if errors.Is(err, io.EOF) {
-				break
+			break
+		}
+
+		blank := strings.TrimSpace(line) == ""
+
+		if buf.Len() == 0 {
+			if blank {
+				continue
+			}
+			if cmd := strings.TrimSpace(line); strings.HasPrefix(cmd, ":") {
+				if !s.command(cmd) {
+					break
+				}
+				continue
 			}
 		}
 
-		line = strings.TrimSpace(line)
-		if len(line) == 0 {
-			break
+		if !blank {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
 		}
 
-		if !strings.HasSuffix(line, ";") {
-			line += ";"
+		src := strings.TrimSpace(buf.String())
+		if !blank && !complete(src) {
+			continue
+		}
+		if !strings.HasSuffix(src, ";") {
+			src += ";"
 		}
+		buf.Reset()
 
-		var (
-			lx  *flatlang.Lexer
-			px  *flatlang.Parser
-			res interface{}
-		)
+		if err := s.eval([]byte(src), ""); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// complete reports whether src holds complete statements. Statements are complete once they end with ';', and do
+// not leave any string or bracket unterminated.
+func complete(src string) bool {
+	if !strings.HasSuffix(src, ";") {
+		return false
+	}
+	_, err := flatlang.Lex([]byte(src), "")
+	var diag flatlang.Diagnostic
+	return !errors.As(err, &diag) || !strings.HasSuffix(diag.Message, "is not terminated")
+}
+
+// session holds the variables and builtins available to the inputs of an interactive session.
+type session struct {
+	out io.Writer
+	ex  *flatlang.Evaluator
+}
+
+func newSession(out io.Writer) *session {
+	s := &session{out: out}
+	s.reset()
+	return s
+}
+
+func (s *session) reset() {
+	s.ex = flatlang.NewEval(nil)
+	check(s.ex.RegisterBuiltins(flatlang.Std(s.out)))
+}
+
+// eval evaluates the program src, printing the value of each statement that evaluates to a value.
+func (s *session) eval(src []byte, path string) error {
+	lx, err := flatlang.Lex(src, path)
+	if err != nil {
+		return err
+	}
+	px, err := flatlang.Parse(lx)
+	if err != nil {
+		return errors.New(lx.Render(err))
+	}
 
-		lx, err = flatlang.Lex([]byte(line), "")
+	s.ex.SetLexer(lx)
+
+	res, err := s.ex.Eval(px.Result)
+	if err != nil {
+		return errors.New(lx.Render(err))
+	}
+
+	for _, val := range res.([]interface{}) {
+		if val != nil {
+			fmt.Fprintf(s.out, "%v\n", val)
+		}
+	}
+	return nil
+}
+
+var commands = []struct {
+	name, args, help string
+}{
+	{name: "help", help: "list all commands"},
+	{name: "vars", help: "list all variables and their values"},
+	{name: "builtins", help: "list all builtins and their signatures"},
+	{name: "load", args: " <file.fbs>", help: "evaluate a file, keeping the variables it assigns"},
+	{name: "reset", help: "clear all variables"},
+	{name: "quit", help: "exit the session"},
+}
+
+// command runs the command cmd. It reports whether the session should carry on.
+func (s *session) command(cmd string) bool {
+	fields := strings.Fields(strings.TrimPrefix(cmd, ":"))
+	if len(fields) == 0 {
+		fields = []string{"help"}
+	}
+
+	switch fields[0] {
+	case "help":
+		for _, c := range commands {
+			fmt.Fprintf(s.out, "  :%-20s %s\n", c.name+c.args, c.help)
+		}
+	case "vars":
+		for _, name := range s.ex.Vars() {
+			val, _ := s.ex.Lookup(name)
+			fmt.Fprintf(s.out, "%s = %v\n", name, val)
+		}
+	case "builtins":
+		for _, name := range s.ex.Builtins() {
+			t, _ := s.ex.BuiltinType(name)
+			fmt.Fprintf(s.out, "%s %v\n", name, t)
+		}
+	case "load":
+		if len(fields) != 2 {
+			log.Println("usage: :load <file.fbs>")
+			break
+		}
+		src, err := ioutil.ReadFile(fields[1])
 		if err == nil {
-			px, err = flatlang.Parse(lx)
-			if err == nil {
-				ex := flatlang.NewEval(lx)
-				ex.RegisterBuiltin("print", func(items ...interface{}) {
-					fmt.Println(items...)
-				})
-				ex.RegisterBuiltin("printf", func(format string, items ...interface{}) {
-					fmt.Printf(format, items...)
-				})
-				res, err = ex.Eval(px.Result)
-				if err == nil {
-					vals, ok := res.([]interface{})
-					if ok && len(vals) > 0 {
-						for _, val := range vals {
-							fmt.Printf("%v\n", val)
-						}
-					}
-					continue
-				}
-			}
+			err = s.eval(src, fields[1])
 		}
-		log.Println(err)
+		if err != nil {
+			log.Println(err)
+		}
+	case "reset":
+		s.reset()
+	case "quit":
+		return false
+	default:
+		log.Printf("unknown command :%s, type :help to list all commands", fields[0])
+	}
+	return true
+}
+
+// completer completes the names of commands, variables and builtins.
+type completer struct {
+	s *session
+}
+
+func (c completer) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && isIdent(line[start-1]) {
+		start--
 	}
+	prefix := string(line[start:pos])
+
+	var names []string
+	if start == 1 && line[0] == ':' {
+		for _, cmd := range commands {
+			names = append(names, cmd.name)
+		}
+	} else {
+		names = append(c.s.ex.Vars(), c.s.ex.Builtins()...)
+	}
+
+	var res [][]rune
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, []rune(name[len(prefix):]))
+		}
+	}
+	return res, len(line[start:pos])
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	var out strings.Builder
+
+	s := newSession(&out)
+
+	// Variables are kept across inputs.
+
+	require.NoError(t, s.eval([]byte("x = 1;"), ""))
+	require.NoError(t, s.eval([]byte("x = x + 1;\nprint x;"), ""))
+	require.Equal(t, "2\n", out.String())
+
+	// Errors caused by pipelines assigned by an earlier input are rendered against the earlier input.
+
+	require.NoError(t, s.eval([]byte("xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx = 1;\nlong_pipeline_name = printf 1;"), ""))
+	require.EqualError(t, s.eval([]byte("long_pipeline_name;"), ""),
+		"(input):2:29: failed to call method \"printf\": printf: arg 0: cannot convert int 1 to string\n"+
+			"long_pipeline_name = printf 1;\n"+
+			"                            ^")
+
+	s.reset()
+	require.EqualError(t, s.eval([]byte("x;"), ""), "(input):1:1: unknown symbol 'x'\nx;\n^")
+}
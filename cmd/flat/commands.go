@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/lithdew/flatlang"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// builtinSets are the sets of builtins that programs may be evaluated with. Builtins that print do so to w.
+var builtinSets = map[string]func(w io.Writer) map[string]interface{}{
+	"std": flatlang.Std,
+	"load": func(io.Writer) map[string]interface{} {
+		return flatlang.LoadBuiltins(".")
+	},
+}
+
+func runCommand(fs *flag.FlagSet) func(args []string) int {
+	sets := fs.String("builtins", "std", "comma-separated list of builtin sets to evaluate with (available: "+setNames()+")")
+	timeout := fs.Duration("timeout", 0, "stop evaluating each program after the given duration (default: no timeout)")
+	limits := limitFlags(fs)
+
+	return func(args []string) int {
+		fns, err := loadSets(*sets, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flat run: %v\n", err)
+			return exitUsage
+		}
+
+		inputs, err := readInputs(args)
+		if err != nil {
+			return report(err)
+		}
+
+		code := exitOK
+		for _, in := range inputs {
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if *timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, *timeout)
+			}
+			_, _, err := eval(ctx, in, fns, *limits)
+			cancel()
+			if err != nil {
+				code = report(err)
+			}
+		}
+		return code
+	}
+}
+
+// limitFlags registers the flags that bound the evaluation of programs to fs, and returns the limits that the flags
+// are parsed into.
+func limitFlags(fs *flag.FlagSet) *flatlang.Limits {
+	var limits flatlang.Limits
+	fs.IntVar(&limits.MaxSteps, "max-steps", 0, "maximum number of nodes evaluated per program (default: unlimited)")
+	fs.IntVar(&limits.MaxDispatches, "max-calls", 0, "maximum number of builtins called per program (default: unlimited)")
+	fs.IntVar(&limits.MaxStringLen, "max-string-len", 0, "maximum length of strings concatenated by '+' or built by interpolation (default: unlimited)")
+	fs.IntVar(&limits.MaxListLen, "max-list-len", 0, "maximum length of lists concatenated by '+' (default: unlimited)")
+	return &limits
+}
+
+// loadSets returns the builtins of the comma-separated list of builtin sets names. Builtins that print do so to w.
+func loadSets(names string, w io.Writer) (map[string]interface{}, error) {
+	fns := make(map[string]interface{})
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		set, exists := builtinSets[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown builtin set %q (available: %s)", name, setNames())
+		}
+		for name, fn := range set(w) {
+			fns[name] = fn
+		}
+	}
+	return fns, nil
+}
+
+func setNames() string {
+	names := make([]string, 0, len(builtinSets))
+	for name := range builtinSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// eval evaluates in with the builtins fns within limits, stopping once ctx is done. It returns the value of each
+// top-level statement.
+func eval(ctx context.Context, in input, fns map[string]interface{}, limits flatlang.Limits) (*flatlang.Evaluator, []interface{}, error) {
+	lx, px, err := parse(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	ex := flatlang.NewEval(lx)
+	ex.Limits = limits
+	if err := ex.RegisterBuiltins(fns); err != nil {
+		return nil, nil, err
+	}
+	res, err := ex.EvalContext(ctx, px.Result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s", lx.Render(err))
+	}
+	return ex, res.([]interface{}), nil
+}
+
+func checkCommand(fs *flag.FlagSet) func(args []string) int {
+	sets := fs.String("builtins", "std", "comma-separated list of builtin sets to check calls against (available: "+setNames()+")")
+	syntax := fs.Bool("syntax-only", false, "only lex and parse programs without checking them against any builtins")
+	timeout := fs.Duration("timeout", 0, "stop evaluating the known expressions of each program after the given duration (default: no timeout)")
+	limits := limitFlags(fs)
+
+	return func(args []string) int {
+		fns, err := loadSets(*sets, ioutil.Discard)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flat check: %v\n", err)
+			return exitUsage
+		}
+
+		inputs, err := readInputs(args)
+		if err != nil {
+			return report(err)
+		}
+
+		code := exitOK
+		for _, in := range inputs {
+			lx, px, err := parse(in)
+			if err != nil {
+				code = report(err)
+				continue
+			}
+			if *syntax {
+				continue
+			}
+			ex := flatlang.NewEval(lx)
+			ex.Limits = *limits
+			if err := ex.RegisterBuiltins(fns); err != nil {
+				return report(err)
+			}
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if *timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, *timeout)
+			}
+			err = ex.CheckContext(ctx, px.Result)
+			cancel()
+			if err != nil {
+				code = report(fmt.Errorf("%s", lx.Render(err)))
+			}
+		}
+		return code
+	}
+}
+
+func fmtCommand(fs *flag.FlagSet) func(args []string) int {
+	list := fs.Bool("l", false, "list files whose formatting differs from flat fmt's, exiting with status 1 should there be any")
+	write := fs.Bool("w", false, "write result to (source) file instead of stdout")
+
+	return func(args []string) int {
+		if *write && len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "flat fmt: cannot use -w with standard input")
+			return exitUsage
+		}
+
+		inputs, err := readInputs(args)
+		if err != nil {
+			return report(err)
+		}
+
+		code := exitOK
+		for _, in := range inputs {
+			res, err := flatlang.Format(in.src)
+			if err != nil {
+				code = report(fmt.Errorf("%s: %w", in.path, err))
+				continue
+			}
+			switch {
+			case *list:
+				if !bytes.Equal(in.src, res) {
+					fmt.Println(in.path)
+					code = exitFailure
+				}
+			case *write:
+				if bytes.Equal(in.src, res) {
+					continue
+				}
+				if err := ioutil.WriteFile(in.path, res, 0644); err != nil {
+					code = report(err)
+				}
+			default:
+				os.Stdout.Write(res)
+			}
+		}
+		return code
+	}
+}
+
+func exportCommand(fs *flag.FlagSet) func(args []string) int {
+	format := fs.String("o", "json", "format to export to (available: json, yaml, toml)")
+	vars := fs.String("vars", "", "comma-separated list of variables to export (default: all variables)")
+	results := fs.Bool("results", false, "export the values of top-level statements under \""+flatlang.ResultsKey+"\"")
+	opaque := fs.String("opaque", "error", "how to export constraints and pipelines (available: error, string, omit)")
+
+	return func(args []string) int {
+		if len(args) > 1 {
+			fs.Usage()
+			return exitUsage
+		}
+
+		opts := flatlang.ExportOptions{}
+
+		var err error
+		if opts.Format, err = flatlang.ParseDataFormat(*format); err != nil {
+			fmt.Fprintf(os.Stderr, "flat export: %v\n", err)
+			return exitUsage
+		}
+		switch *opaque {
+		case "error":
+			opts.Opaque = flatlang.OpaqueError
+		case "string":
+			opts.Opaque = flatlang.OpaqueString
+		case "omit":
+			opts.Opaque = flatlang.OpaqueOmit
+		default:
+			fmt.Fprintf(os.Stderr, "flat export: unknown opaque policy %q\n", *opaque)
+			return exitUsage
+		}
+		for _, name := range strings.Split(*vars, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				opts.Vars = append(opts.Vars, name)
+			}
+		}
+
+		inputs, err := readInputs(args)
+		if err != nil {
+			return report(err)
+		}
+		ex, res, err := eval(context.Background(), inputs[0], flatlang.Std(os.Stderr), flatlang.Limits{})
+		if err != nil {
+			return report(err)
+		}
+		if *results {
+			opts.Results = res
+		}
+
+		buf, err := ex.Export(opts)
+		if err != nil {
+			return report(fmt.Errorf("%s: failed to export: %w", inputs[0].path, err))
+		}
+		os.Stdout.Write(buf)
+		return exitOK
+	}
+}
+
+func lexCommand(fs *flag.FlagSet) func(args []string) int {
+	return func(args []string) int {
+		inputs, err := readInputs(args)
+		if err != nil {
+			return report(err)
+		}
+
+		code := exitOK
+		for _, in := range inputs {
+			lx, err := flatlang.Lex(in.src, in.path)
+			if err != nil {
+				code = report(err)
+				continue
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+			for _, tok := range lx.Tokens {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", lx.Position(tok.Pos), flatlang.Repr(tok.Sym), in.src[tok.Pos:tok.End])
+			}
+			if err := w.Flush(); err != nil {
+				return report(err)
+			}
+		}
+		return code
+	}
+}
+
+func parseCommand(fs *flag.FlagSet) func(args []string) int {
+	return func(args []string) int {
+		inputs, err := readInputs(args)
+		if err != nil {
+			return report(err)
+		}
+
+		code := exitOK
+		for _, in := range inputs {
+			_, px, err := parse(in)
+			if err != nil {
+				code = report(err)
+				continue
+			}
+			fmt.Println(px.Format())
+		}
+		return code
+	}
+}
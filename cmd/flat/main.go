@@ -0,0 +1,130 @@
+// Command flat lexes, parses, checks, formats, evaluates and exports flatlang programs.
+//
+// Usage:
+//
+//	flat <command> [flags] [path ...]
+//
+// Commands read standard input should no paths be given. flat exits with status 1 should any program fail to be
+// lexed, parsed, checked or evaluated, and with status 2 should it be invoked incorrectly.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/lithdew/flatlang"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	exitOK      = 0
+	exitFailure = 1 // a program failed to be lexed, parsed, checked or evaluated
+	exitUsage   = 2
+)
+
+// command is a subcommand of flat. setup registers the flags of the command, and returns a func that runs the
+// command against the args left after parsing flags.
+type command struct {
+	name  string
+	usage string
+	help  string
+	setup func(fs *flag.FlagSet) func(args []string) int
+}
+
+var commands = []*command{
+	{name: "run", usage: "[-builtins sets] [path ...]", help: "evaluate programs", setup: runCommand},
+	{name: "check", usage: "[-builtins sets] [-syntax-only] [path ...]", help: "report all errors found in programs without evaluating them", setup: checkCommand},
+	{name: "fmt", usage: "[-l] [-w] [path ...]", help: "format programs", setup: fmtCommand},
+	{name: "export", usage: "[-o format] [-vars names] [-results] [-opaque policy] [path]", help: "evaluate a program and export its variables", setup: exportCommand},
+	{name: "lex", usage: "[path ...]", help: "print the tokens of programs", setup: lexCommand},
+	{name: "parse", usage: "[path ...]", help: "print the syntax trees of programs", setup: parseCommand},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: flat <command> [flags] [path ...]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.help)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun 'flat <command> -h' for the flags of a command\n")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitUsage
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(os.Stderr, "usage: flat %s %s\n", cmd.name, cmd.usage)
+			fs.PrintDefaults()
+		}
+		action := cmd.setup(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			if err == flag.ErrHelp {
+				return exitOK
+			}
+			return exitUsage
+		}
+		return action(fs.Args())
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage()
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "flat: unknown command %q\n", args[0])
+	usage()
+	return exitUsage
+}
+
+// input is the source code of a program, alongside the path it was read from.
+type input struct {
+	path string
+	src  []byte
+}
+
+// readInputs reads the programs at paths, or reads a single program from standard input should there be no paths.
+func readInputs(paths []string) ([]input, error) {
+	if len(paths) == 0 {
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return []input{{path: "<stdin>", src: src}}, nil
+	}
+	inputs := make([]input, 0, len(paths))
+	for _, path := range paths {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input{path: path, src: src})
+	}
+	return inputs, nil
+}
+
+// parse lexes and parses in. Errors are rendered alongside the source code they occurred at.
+func parse(in input) (*flatlang.Lexer, *flatlang.Parser, error) {
+	lx, err := flatlang.Lex(in.src, in.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	px, err := flatlang.Parse(lx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s", lx.Render(err))
+	}
+	return lx, px, nil
+}
+
+// report prints err to standard error, and returns the exit status of a command that failed with err.
+func report(err error) int {
+	fmt.Fprintln(os.Stderr, err)
+	return exitFailure
+}
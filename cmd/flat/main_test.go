@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flat")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"valid.fbs":       "greeting = 'hello';\nprint greeting;\n",
+		"unformatted.fbs": "greeting='hello';\n",
+		"invalid.fbs":     "greeting = ;\n",
+		"failing.fbs":     "x = nope;\n",
+		"unknown.fbs":     "sql 'select 1';\n",
+		"arity.fbs":       "printf;\n",
+		"doubling.fbs":    "x = 'x';\n",
+	}
+	for i := 0; i < 16; i++ {
+		files["doubling.fbs"] += "x = x + x;\n"
+	}
+	for name, src := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	cases := []struct {
+		args []string
+		code int
+	}{
+		{args: nil, code: exitUsage},
+		{args: []string{"help"}, code: exitOK},
+		{args: []string{"nope"}, code: exitUsage},
+		{args: []string{"run", "-h"}, code: exitOK},
+		{args: []string{"run", "-nope"}, code: exitUsage},
+
+		{args: []string{"run", "valid.fbs"}, code: exitOK},
+		{args: []string{"run", "invalid.fbs"}, code: exitFailure},
+		{args: []string{"run", "failing.fbs"}, code: exitFailure},
+		{args: []string{"run", "valid.fbs", "failing.fbs"}, code: exitFailure},
+		{args: []string{"run", "missing.fbs"}, code: exitFailure},
+		{args: []string{"run", "-builtins", "nope", "valid.fbs"}, code: exitUsage},
+		{args: []string{"run", "-builtins", "", "valid.fbs"}, code: exitFailure},
+		{args: []string{"run", "-max-steps", "1", "valid.fbs"}, code: exitFailure},
+
+		{args: []string{"check", "valid.fbs"}, code: exitOK},
+		{args: []string{"check", "invalid.fbs"}, code: exitFailure},
+		{args: []string{"check", "unknown.fbs"}, code: exitFailure},
+		{args: []string{"check", "arity.fbs"}, code: exitFailure},
+		{args: []string{"check", "-builtins", "std", "unknown.fbs"}, code: exitFailure},
+		{args: []string{"check", "-syntax-only", "unknown.fbs"}, code: exitOK},
+		{args: []string{"check", "-syntax-only", "invalid.fbs"}, code: exitFailure},
+		{args: []string{"check", "-builtins", "nope", "valid.fbs"}, code: exitUsage},
+		{args: []string{"check", "doubling.fbs"}, code: exitOK},
+		{args: []string{"check", "-max-string-len", "1024", "doubling.fbs"}, code: exitFailure},
+		{args: []string{"check", "-max-steps", "2", "doubling.fbs"}, code: exitFailure},
+		{args: []string{"check", "-timeout", "1h", "doubling.fbs"}, code: exitOK},
+
+		{args: []string{"fmt", "-l", "valid.fbs"}, code: exitOK},
+		{args: []string{"fmt", "-l", "unformatted.fbs"}, code: exitFailure},
+		{args: []string{"fmt", "invalid.fbs"}, code: exitFailure},
+		{args: []string{"fmt", "-w"}, code: exitUsage},
+
+		{args: []string{"export", "valid.fbs"}, code: exitOK},
+		{args: []string{"export", "-o", "yaml", "-vars", "greeting", "valid.fbs"}, code: exitOK},
+		{args: []string{"export", "-o", "xml", "valid.fbs"}, code: exitUsage},
+		{args: []string{"export", "-opaque", "nope", "valid.fbs"}, code: exitUsage},
+		{args: []string{"export", "valid.fbs", "valid.fbs"}, code: exitUsage},
+		{args: []string{"export", "failing.fbs"}, code: exitFailure},
+
+		{args: []string{"lex", "valid.fbs"}, code: exitOK},
+		{args: []string{"parse", "valid.fbs"}, code: exitOK},
+		{args: []string{"parse", "invalid.fbs"}, code: exitFailure},
+	}
+
+	for _, test := range cases {
+		args := make([]string, 0, len(test.args))
+		for _, arg := range test.args {
+			if _, exists := files[arg]; exists || arg == "missing.fbs" {
+				arg = filepath.Join(dir, arg)
+			}
+			args = append(args, arg)
+		}
+		require.Equal(t, test.code, run(args), strings.Join(test.args, " "))
+	}
+}
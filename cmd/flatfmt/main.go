@@ -0,0 +1,81 @@
+// Command flatfmt formats flatlang programs.
+//
+// Without any paths, flatfmt formats standard input to standard output. Otherwise, it formats each file at the
+// paths given.
+//
+// Usage:
+//
+//	flatfmt [-l] [-w] [path ...]
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/lithdew/flatlang"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	list  = flag.Bool("l", false, "list files whose formatting differs from flatfmt's")
+	write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: flatfmt [flags] [path ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "flatfmt: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err == nil {
+			err = process("<standard input>", src)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	code := 0
+	for _, path := range flag.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = process(path, src)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func process(path string, src []byte) error {
+	res, err := flatlang.Format(src)
+	if err != nil {
+		return err
+	}
+	if *list {
+		if !bytes.Equal(src, res) {
+			fmt.Println(path)
+		}
+		return nil
+	}
+	if *write {
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		return ioutil.WriteFile(path, res, 0644)
+	}
+	_, err = os.Stdout.Write(res)
+	return err
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"github.com/lithdew/flatlang"
+	"github.com/lithdew/flatlang/ast"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is an open text document, alongside the result of lexing and parsing it.
+type document struct {
+	uri   string
+	text  []byte
+	lines []int // offset of the first byte of each line
+
+	prog  *ast.Program // nil should the document have failed to lex
+	diags []flatlang.Diagnostic
+}
+
+func newDocument(uri string, text []byte) *document {
+	d := &document{uri: uri, text: text, lines: []int{0}}
+	for i, c := range text {
+		if c == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+
+	lx, err := flatlang.Lex(text, uri)
+	if err != nil {
+		d.diags = toDiagnostics(err)
+		return d
+	}
+	px, err := flatlang.Parse(lx)
+	if err != nil {
+		d.diags = toDiagnostics(err)
+	}
+	d.prog = px.AST
+	return d
+}
+
+func toDiagnostics(err error) []flatlang.Diagnostic {
+	var (
+		diags flatlang.Diagnostics
+		diag  flatlang.Diagnostic
+	)
+	switch {
+	case errors.As(err, &diags):
+		return diags
+	case errors.As(err, &diag):
+		return []flatlang.Diagnostic{diag}
+	}
+	return []flatlang.Diagnostic{{Severity: flatlang.SeverityError, Message: err.Error()}}
+}
+
+// position returns the LSP position of offset. LSP positions count characters in UTF-16 code units.
+func (d *document) position(offset int) position {
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+	if line < 0 {
+		line = 0
+	}
+	char := 0
+	for _, r := range string(d.text[d.lines[line]:offset]) {
+		char++
+		if r >= 0x10000 {
+			char++
+		}
+	}
+	return position{Line: line, Character: char}
+}
+
+// offset returns the offset of the LSP position pos.
+func (d *document) offset(pos position) int {
+	if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	offset := d.lines[pos.Line]
+	for char := 0; char < pos.Character && offset < len(d.text); {
+		r, size := utf8.DecodeRune(d.text[offset:])
+		if r == '\n' {
+			break
+		}
+		char++
+		if r >= 0x10000 {
+			char++
+		}
+		offset += size
+	}
+	return offset
+}
+
+func (d *document) span(pos, end int) span {
+	return span{Start: d.position(pos), End: d.position(end)}
+}
+
+func (d *document) diagnostics() []diagnostic {
+	res := make([]diagnostic, 0, len(d.diags))
+	for _, diag := range d.diags {
+		msg := diag.Message
+		if len(diag.Expected) > 0 {
+			msg += ", expecting " + strings.Join(diag.Expected, " or ")
+		}
+		severity := severityError
+		if diag.Severity == flatlang.SeverityWarning {
+			severity = severityWarning
+		}
+		res = append(res, diagnostic{
+			Range:    d.span(offsetOf(diag.Pos), offsetOf(diag.End)),
+			Severity: severity,
+			Source:   "flatls",
+			Message:  msg,
+		})
+	}
+	return res
+}
+
+func offsetOf(pos token.Position) int {
+	if !pos.IsValid() {
+		return 0
+	}
+	return pos.Offset
+}
+
+// identAt returns the identifier that refers to a variable or builtin at offset. Keys of map literals are not
+// considered to be identifiers.
+func (d *document) identAt(offset int) *ast.Ident {
+	if d.prog == nil {
+		return nil
+	}
+	var res *ast.Ident
+	ast.Inspect(d.prog, func(n ast.Node) bool {
+		if res != nil || offset < n.Pos() || offset > n.End() {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.Ident:
+			res = n
+		case *ast.Field:
+			ast.Inspect(n.Value, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok && offset >= n.Pos() && offset <= n.End() {
+					res = ident
+				}
+				return res == nil
+			})
+			return false
+		}
+		return true
+	})
+	return res
+}
+
+// definition returns the assignment that defines the variable referred to by ident. Variables are resolved the same
+// way that they are when programs are evaluated: the last assignment to the variable before the statement holding
+// ident is returned. Should there be no such assignment, the first assignment after ident is returned instead.
+func (d *document) definition(ident *ast.Ident) *ast.Assign {
+	var res *ast.Assign
+	for _, stmt := range d.prog.Stmts {
+		assign, ok := stmt.(*ast.Assign)
+		if !ok || assign.Name.Name != ident.Name {
+			continue
+		}
+		if assign.Pos() < ident.Pos() && ident.End() <= assign.End() {
+			continue
+		}
+		if assign.Pos() > ident.Pos() && res != nil {
+			break
+		}
+		res = assign
+		if assign.Pos() > ident.Pos() {
+			break
+		}
+	}
+	return res
+}
+
+// variables returns the names of all variables assigned to in the document in sorted order.
+func (d *document) variables() []string {
+	if d.prog == nil {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	var names []string
+	for _, stmt := range d.prog.Stmts {
+		if assign, ok := stmt.(*ast.Assign); ok {
+			if _, exists := seen[assign.Name.Name]; !exists {
+				seen[assign.Name.Name] = struct{}{}
+				names = append(names, assign.Name.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDocumentOffsets(t *testing.T) {
+	src := "a = 'é😀x';\nb = a;\n\nc = `${b}`;"
+	doc := newDocument("file:///test.fbs", []byte(src))
+
+	// Offsets of runes round-trip through LSP positions, including offsets of runes that take up two UTF-16 code
+	// units.
+
+	for offset := 0; offset <= len(src); offset++ {
+		if offset < len(src) && !utf8.RuneStart(src[offset]) {
+			continue
+		}
+		require.Equal(t, offset, doc.offset(doc.position(offset)), "offset %d", offset)
+	}
+
+	x := strings.Index(src, "x")
+	require.Equal(t, position{Line: 0, Character: 8}, doc.position(x))
+	require.Equal(t, position{Line: 1, Character: 0}, doc.position(strings.Index(src, "b")))
+	require.Equal(t, position{Line: 2, Character: 0}, doc.position(strings.Index(src, "\n\n")+1))
+
+	// Positions past the end of a line are clamped to the end of the line, and positions past the last line to
+	// the end of the document.
+
+	require.Equal(t, strings.Index(src, "\n"), doc.offset(position{Line: 0, Character: 100}))
+	require.Equal(t, len(src), doc.offset(position{Line: 10, Character: 0}))
+}
+
+func TestDocumentDefinition(t *testing.T) {
+	src := "print a;\na = 1;\nb = a;\na = a + 1;\nm = {a: a};\nprint b c;"
+	doc := newDocument("file:///test.fbs", []byte(src))
+	require.Empty(t, doc.diags)
+
+	// nth returns the offset of the nth occurrence of sub within src.
+
+	nth := func(sub string, n int) int {
+		offset := -1
+		for i := 0; i <= n; i++ {
+			offset += 1 + strings.Index(src[offset+1:], sub)
+		}
+		return offset
+	}
+
+	// defines returns the offset of the assignment that defines the variable referred to at offset, or -1.
+
+	defines := func(offset int) int {
+		ident := doc.identAt(offset)
+		require.NotNil(t, ident, "offset %d", offset)
+		assign := doc.definition(ident)
+		if assign == nil {
+			return -1
+		}
+		return assign.Pos()
+	}
+
+	first, second := nth("a = ", 0), nth("a = ", 1)
+
+	require.Equal(t, first, defines(nth("a", 1)))          // 'print a' refers to the first assignment after it
+	require.Equal(t, first, defines(nth("a;", 1)))         // 'b = a' refers to the last assignment before it
+	require.Equal(t, first, defines(nth("a + 1", 0)))      // 'a = a + 1' refers to the assignment before it
+	require.Equal(t, second, defines(nth("a}", 0)))        // '{a: a}' refers to the redeclaration
+	require.Equal(t, second, defines(second))              // the name of an assignment refers to the assignment
+	require.Equal(t, nth("b = ", 0), defines(nth("b", 1))) // 'print b' refers to the assignment of b
+	require.Equal(t, -1, defines(nth("c;", 0)))            // 'c' is not assigned to
+
+	// Keys of map literals do not refer to variables.
+
+	require.Nil(t, doc.identAt(nth("a:", 0)))
+
+	require.Equal(t, []string{"a", "b", "m"}, doc.variables())
+}
@@ -0,0 +1,37 @@
+// Command flatls is a language server for flatlang that communicates over standard input and output.
+//
+// It publishes lexer and parser diagnostics, completes the names of builtins and variables, shows the Go signatures
+// of builtins on hover, and jumps from a use of a variable to its definition.
+package main
+
+import (
+	"errors"
+	"github.com/lithdew/flatlang"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+func check(err error) {
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+
+	ex := flatlang.NewEval(nil)
+	check(ex.RegisterBuiltins(flatlang.Std(ioutil.Discard)))
+
+	s := newServer(os.Stdout, ex)
+	if err := s.serve(os.Stdin); err != nil && !errors.Is(err, errExit) {
+		log.Fatal(err)
+	}
+
+	// The exit code is 0 should the client have asked the server to shut down before exiting, and 1 otherwise.
+
+	if !s.shutdown {
+		os.Exit(1)
+	}
+}
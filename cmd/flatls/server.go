@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lithdew/flatlang"
+	"github.com/lithdew/flatlang/ast"
+	"io"
+	"strings"
+)
+
+// errExit is returned by serve once the client asks the server to exit.
+var errExit = errors.New("exit")
+
+type server struct {
+	w        io.Writer
+	ex       *flatlang.Evaluator // holds the builtins that are known to the server
+	docs     map[string]*document
+	shutdown bool
+}
+
+func newServer(w io.Writer, ex *flatlang.Evaluator) *server {
+	return &server{w: w, ex: ex, docs: make(map[string]*document)}
+}
+
+// serve reads and handles messages from r until r is closed, or until the client asks the server to exit.
+func (s *server) serve(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		buf, err := readMessage(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(buf, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		res, err := s.handle(req)
+		if errors.Is(err, errExit) {
+			return err
+		}
+
+		// Notifications are not replied to.
+
+		if req.ID == nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var rerr *responseError
+		if err != nil && !errors.As(err, &rerr) {
+			return err
+		}
+		if err := s.reply(req.ID, res, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) reply(id *json.RawMessage, res interface{}, rerr *responseError) error {
+	if rerr != nil {
+		return writeMessage(s.w, response{JSONRPC: "2.0", ID: id, Error: rerr})
+	}
+	buf, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.w, response{JSONRPC: "2.0", ID: id, Result: buf})
+}
+
+func (s *server) notify(method string, params interface{}) error {
+	return writeMessage(s.w, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *server) handle(req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   textDocumentSyncFull,
+				HoverProvider:      true,
+				DefinitionProvider: true,
+			},
+			ServerInfo: serverInfo{Name: "flatls"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, errExit
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		return nil, s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	}
+	if req.ID == nil {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+func unmarshalParams(req request, params interface{}) error {
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// update replaces the text of the document at uri, and publishes the diagnostics of the document.
+func (s *server) update(uri, text string) error {
+	doc := newDocument(uri, []byte(text))
+	s.docs[uri] = doc
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.diagnostics(),
+	})
+}
+
+// lookup returns the document and identifier at the position given by params.
+func (s *server) lookup(params textDocumentPositionParams) (*document, *ast.Ident) {
+	doc, exists := s.docs[params.TextDocument.URI]
+	if !exists {
+		return nil, nil
+	}
+	return doc, doc.identAt(doc.offset(params.Position))
+}
+
+func (s *server) hover(params textDocumentPositionParams) interface{} {
+	doc, ident := s.lookup(params)
+	if ident == nil {
+		return nil
+	}
+
+	var contents string
+	if assign := doc.definition(ident); assign != nil {
+		contents = "```flatlang\n" + string(doc.text[assign.Pos():assign.End()]) + "\n```"
+	} else if t, exists := s.ex.BuiltinType(ident.Name); exists {
+		contents = "```go\nfunc " + ident.Name + strings.TrimPrefix(t.String(), "func") + "\n```"
+	} else {
+		return nil
+	}
+
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: contents},
+		Range:    doc.span(ident.Pos(), ident.End()),
+	}
+}
+
+func (s *server) completion(params textDocumentPositionParams) []completionItem {
+	items := make([]completionItem, 0)
+	for _, name := range s.ex.Builtins() {
+		t, _ := s.ex.BuiltinType(name)
+		items = append(items, completionItem{Label: name, Kind: completionKindFunction, Detail: t.String()})
+	}
+	if doc, exists := s.docs[params.TextDocument.URI]; exists {
+		for _, name := range doc.variables() {
+			items = append(items, completionItem{Label: name, Kind: completionKindVariable})
+		}
+	}
+	return items
+}
+
+func (s *server) definition(params textDocumentPositionParams) interface{} {
+	doc, ident := s.lookup(params)
+	if ident == nil {
+		return nil
+	}
+	assign := doc.definition(ident)
+	if assign == nil {
+		return nil
+	}
+	return location{URI: doc.uri, Range: doc.span(assign.Name.Pos(), assign.Name.End())}
+}
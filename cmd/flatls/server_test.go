@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/lithdew/flatlang"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	const uri = "file:///test.fbs"
+
+	ex := flatlang.NewEval(nil)
+	require.NoError(t, ex.RegisterBuiltins(flatlang.Std(ioutil.Discard)))
+
+	// id returns a request ID holding i.
+
+	id := func(i int) *json.RawMessage {
+		raw := json.RawMessage(strconv.Itoa(i))
+		return &raw
+	}
+
+	// params marshals v into the params of a request.
+
+	params := func(v interface{}) json.RawMessage {
+		buf, err := json.Marshal(v)
+		require.NoError(t, err)
+		return buf
+	}
+
+	var in bytes.Buffer
+	for _, req := range []request{
+		{ID: id(1), Method: "initialize", Params: params(struct{}{})},
+		{Method: "textDocument/didOpen", Params: params(didOpenParams{
+			TextDocument: textDocumentItem{URI: uri, Text: "a = 'é😀';\nprint a;"},
+		})},
+		{ID: id(2), Method: "textDocument/definition", Params: params(textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     position{Line: 1, Character: 6},
+		})},
+		{ID: id(3), Method: "textDocument/unknown"},
+		{ID: id(4), Method: "shutdown"},
+		{Method: "exit"},
+	} {
+		req.JSONRPC = "2.0"
+		require.NoError(t, writeMessage(&in, req))
+	}
+
+	var out bytes.Buffer
+
+	s := newServer(&out, ex)
+	require.True(t, errors.Is(s.serve(&in), errExit))
+	require.True(t, s.shutdown)
+
+	// Each request is replied to in order, and the opened document has its diagnostics published.
+
+	r := bufio.NewReader(&out)
+
+	var msgs []map[string]interface{}
+	for {
+		buf, err := readMessage(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf, &msg))
+		msgs = append(msgs, msg)
+	}
+	require.Len(t, msgs, 5)
+
+	require.EqualValues(t, 1, msgs[0]["id"])
+	require.Equal(t, map[string]interface{}{"name": "flatls"}, msgs[0]["result"].(map[string]interface{})["serverInfo"])
+
+	require.Equal(t, "textDocument/publishDiagnostics", msgs[1]["method"])
+	require.Equal(t, map[string]interface{}{"uri": uri, "diagnostics": []interface{}{}}, msgs[1]["params"])
+
+	require.EqualValues(t, 2, msgs[2]["id"])
+	require.Equal(t, map[string]interface{}{
+		"uri": uri,
+		"range": map[string]interface{}{
+			"start": map[string]interface{}{"line": 0.0, "character": 0.0},
+			"end":   map[string]interface{}{"line": 0.0, "character": 1.0},
+		},
+	}, msgs[2]["result"])
+
+	require.EqualValues(t, 3, msgs[3]["id"])
+	require.EqualValues(t, codeMethodNotFound, msgs[3]["error"].(map[string]interface{})["code"])
+
+	require.EqualValues(t, 4, msgs[4]["id"])
+	require.Nil(t, msgs[4]["error"])
+}
+
+func TestReadMessage(t *testing.T) {
+	cases := []struct {
+		src string
+		msg string
+		err string
+	}{
+		{src: "Content-Length: 2\r\n\r\n{}", msg: "{}"},
+		{src: "Content-Length: nope\r\n\r\n{}", err: "malformed content length: strconv.Atoi: parsing \"nope\": invalid syntax"},
+		{src: "Content-Length: -1\r\n\r\n{}", err: "content length -1 is not within [0, 67108864]"},
+		{src: "Content-Length: 1099511627776\r\n\r\n{}", err: "content length 1099511627776 is not within [0, 67108864]"},
+		{src: "Content-Length: 3\r\n\r\n{}", err: "unexpected EOF"},
+	}
+
+	for _, test := range cases {
+		buf, err := readMessage(bufio.NewReader(strings.NewReader(test.src)))
+		if test.err != "" {
+			require.EqualError(t, err, test.err, test.src)
+			continue
+		}
+		require.NoError(t, err, test.src)
+		require.Equal(t, test.msg, string(buf), test.src)
+	}
+}
@@ -0,0 +1,301 @@
+package flatlang
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a value that restricts the set of values an option may take on. Constraints are produced by
+// evaluating the comparison (<, >, <=, >=), negation (!), conjunction (&) and disjunction (|) operators, and may be
+// passed into builtins like any other value.
+type Constraint interface {
+	// Validate returns an error should val not satisfy the constraint.
+	Validate(val interface{}) error
+
+	// String returns the constraint formatted as flatlang source.
+	String() string
+}
+
+type compareConstraint struct {
+	op    NodeType
+	bound interface{}
+}
+
+type equalConstraint struct {
+	val interface{}
+}
+
+type notConstraint struct {
+	c Constraint
+}
+
+type typeConstraint string
+
+type andConstraint []Constraint
+
+type orConstraint []Constraint
+
+// Types are the predeclared type constraints. A value satisfies a type constraint should it be of the named type.
+var Types = map[string]Constraint{
+	"int":    typeConstraint("int"),
+	"float":  typeConstraint("float"),
+	"string": typeConstraint("string"),
+	"bool":   typeConstraint("bool"),
+	"list":   typeConstraint("list"),
+	"map":    typeConstraint("map"),
+}
+
+func newCompareConstraint(op NodeType, bound interface{}) (Constraint, error) {
+	switch bound.(type) {
+	case int64, *big.Int, float64, *big.Rat, string:
+		return compareConstraint{op: op, bound: bound}, nil
+	}
+	return nil, fmt.Errorf("cannot constrain values to be %v '%v'", op, bound)
+}
+
+// toConstraint converts val into a constraint. Values that are not constraints constrain values to be equal to them.
+func toConstraint(val interface{}) Constraint {
+	if c, ok := val.(Constraint); ok {
+		return c
+	}
+	return equalConstraint{val: val}
+}
+
+func newNotConstraint(val interface{}) Constraint {
+	return notConstraint{c: toConstraint(val)}
+}
+
+func newAndConstraint(lhs, rhs interface{}) Constraint {
+	var res andConstraint
+	for _, val := range [...]interface{}{lhs, rhs} {
+		switch c := toConstraint(val).(type) {
+		case andConstraint:
+			res = append(res, c...)
+		default:
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func newOrConstraint(lhs, rhs interface{}) Constraint {
+	var res orConstraint
+	for _, val := range [...]interface{}{lhs, rhs} {
+		switch c := toConstraint(val).(type) {
+		case orConstraint:
+			res = append(res, c...)
+		default:
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func (c compareConstraint) Validate(val interface{}) error {
+	res, ok := compare(val, c.bound)
+	if !ok {
+		return fmt.Errorf("%s is not comparable to %s", repr(val), repr(c.bound))
+	}
+
+	switch c.op {
+	case OpNode + '<':
+		ok = res < 0
+	case OpNode + '>':
+		ok = res > 0
+	case OpNode + lte:
+		ok = res <= 0
+	case OpNode + gte:
+		ok = res >= 0
+	}
+
+	if !ok {
+		return fmt.Errorf("%s does not satisfy %s", repr(val), c)
+	}
+	return nil
+}
+
+func (c compareConstraint) String() string { return c.op.String() + repr(c.bound) }
+
+func (c equalConstraint) Validate(val interface{}) error {
+	if !equal(val, c.val) {
+		return fmt.Errorf("%s does not satisfy %s", repr(val), c)
+	}
+	return nil
+}
+
+func (c equalConstraint) String() string { return repr(c.val) }
+
+func (c typeConstraint) Validate(val interface{}) error {
+	if name := typeName(val); name != string(c) {
+		return fmt.Errorf("expected %s, got %s %s", string(c), name, repr(val))
+	}
+	return nil
+}
+
+func (c typeConstraint) String() string { return string(c) }
+
+func (c notConstraint) Validate(val interface{}) error {
+	if c.c.Validate(val) == nil {
+		return fmt.Errorf("%s does not satisfy %s", repr(val), c)
+	}
+	return nil
+}
+
+func (c notConstraint) String() string {
+	switch c.c.(type) {
+	case andConstraint, orConstraint:
+		return "!(" + c.c.String() + ")"
+	}
+	return "!" + c.c.String()
+}
+
+func (c andConstraint) Validate(val interface{}) error {
+	for _, cc := range c {
+		if err := cc.Validate(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c andConstraint) String() string { return joinConstraints(c, " & ") }
+
+func (c orConstraint) Validate(val interface{}) error {
+	for _, cc := range c {
+		if cc.Validate(val) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s does not satisfy %s", repr(val), c)
+}
+
+func (c orConstraint) String() string { return joinConstraints(c, " | ") }
+
+func joinConstraints(cs []Constraint, sep string) string {
+	strs := make([]string, 0, len(cs))
+	for _, c := range cs {
+		switch c.(type) {
+		case andConstraint, orConstraint:
+			strs = append(strs, "("+c.String()+")")
+		default:
+			strs = append(strs, c.String())
+		}
+	}
+	return strings.Join(strs, sep)
+}
+
+// compare compares a against b, returning -1, 0 or +1 should a be less than, equal to or greater than b. It reports
+// false should a and b not be comparable.
+func compare(a, b interface{}) (int, bool) {
+	if a, ok := a.(string); ok {
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+		return 0, false
+	}
+	return compareNumbers(a, b)
+}
+
+// equal reports whether a and b are equal. Ints and floats that represent the same number are considered equal.
+func equal(a, b interface{}) bool {
+	if res, ok := compare(a, b); ok {
+		return res == 0
+	}
+	switch a := a.(type) {
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, exists := b[k]
+			if !exists || !equal(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// typeName returns the name of the type of val.
+func typeName(val interface{}) string {
+	switch val.(type) {
+	case int64, *big.Int:
+		return "int"
+	case float64, *big.Rat:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	case Constraint:
+		return "constraint"
+	case Pipeline:
+		return "pipeline"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+// repr formats val as flatlang source.
+func repr(val interface{}) string {
+	switch val := val.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case *big.Int:
+		return val.String()
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case *big.Rat:
+		return formatRat(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case []interface{}:
+		elems := make([]string, 0, len(val))
+		for _, elem := range val {
+			elems = append(elems, repr(elem))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]string, 0, len(val))
+		for _, key := range keys {
+			fields = append(fields, key+": "+repr(val[key]))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case Constraint:
+		return val.String()
+	case Pipeline:
+		return val.String()
+	}
+	return fmt.Sprintf("%v", val)
+}
@@ -0,0 +1,97 @@
+package flatlang
+
+import (
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestConstraint(t *testing.T) {
+	src := []byte(`require {offset: >=0, limit: >=0 & <=1024} > set "hello"|>=3 > check !'';`)
+
+	lx, err := Lex(src, "")
+	require.NoError(t, err)
+
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	ex := NewEval(lx)
+
+	var constraints []Constraint
+
+	require.NoError(t, ex.RegisterBuiltin("require", func(fields map[string]interface{}) {
+		constraints = append(constraints, fields["offset"].(Constraint), fields["limit"].(Constraint))
+	}))
+	require.NoError(t, ex.RegisterBuiltin("set", func(c Constraint) { constraints = append(constraints, c) }))
+	require.NoError(t, ex.RegisterBuiltin("check", func(c Constraint) { constraints = append(constraints, c) }))
+
+	_, err = ex.Eval(px.Result)
+	require.NoError(t, err)
+	require.Len(t, constraints, 4)
+
+	offset, limit, set, check := constraints[0], constraints[1], constraints[2], constraints[3]
+
+	require.Equal(t, ">=0", offset.String())
+	require.NoError(t, offset.Validate(int64(0)))
+	require.NoError(t, offset.Validate(0.5))
+	require.Error(t, offset.Validate(int64(-1)))
+	require.Error(t, offset.Validate("0"))
+
+	require.Equal(t, ">=0 & <=1024", limit.String())
+	require.NoError(t, limit.Validate(int64(1024)))
+	require.Error(t, limit.Validate(int64(1025)))
+
+	// NaN is not ordered with respect to any number, and therefore does not satisfy any comparison.
+
+	require.EqualError(t, offset.Validate(math.NaN()), "NaN is not comparable to 0")
+	require.Error(t, limit.Validate(math.NaN()))
+	require.False(t, equal(math.NaN(), math.NaN()))
+
+	require.Equal(t, `"hello" | >=3`, set.String())
+	require.NoError(t, set.Validate("hello"))
+	require.NoError(t, set.Validate(int64(3)))
+	require.Error(t, set.Validate("world"))
+	require.Error(t, set.Validate(int64(2)))
+
+	require.Equal(t, `!""`, check.String())
+	require.NoError(t, check.Validate("text"))
+	require.Error(t, check.Validate(""))
+}
+
+func TestTypeConstraint(t *testing.T) {
+	src := []byte(`schema = {author: string, content: string & !'', tags: list | map, score: int | float};`)
+
+	lx, err := Lex(src, "")
+	require.NoError(t, err)
+
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	ex := NewEval(lx)
+
+	_, err = ex.Eval(px.Result)
+	require.NoError(t, err)
+
+	schema := ex.sym["schema"].(map[string]interface{})
+
+	author := schema["author"].(Constraint)
+	require.Equal(t, "string", author.String())
+	require.NoError(t, author.Validate("lithdew"))
+	require.EqualError(t, author.Validate(int64(1)), "expected string, got int 1")
+
+	content := schema["content"].(Constraint)
+	require.Equal(t, `string & !""`, content.String())
+	require.NoError(t, content.Validate("hello"))
+	require.Error(t, content.Validate(""))
+	require.EqualError(t, content.Validate(true), "expected string, got bool true")
+
+	tags := schema["tags"].(Constraint)
+	require.NoError(t, tags.Validate([]interface{}{"a"}))
+	require.NoError(t, tags.Validate(map[string]interface{}{}))
+	require.Error(t, tags.Validate("a"))
+
+	score := schema["score"].(Constraint)
+	require.NoError(t, score.Validate(int64(1)))
+	require.NoError(t, score.Validate(1.5))
+	require.EqualError(t, score.Validate("1"), `"1" does not satisfy int | float`)
+}
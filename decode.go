@@ -0,0 +1,488 @@
+package flatlang
+
+import (
+	"fmt"
+	"github.com/lithdew/flatlang/ast"
+	"go/token"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal evaluates the flatlang program src, and decodes its variables into the value pointed to by v. It is
+// equivalent to calling Decode on a zero Decoder.
+func Unmarshal(src []byte, v interface{}) error {
+	return Decoder{}.Decode(src, v)
+}
+
+// Decoder evaluates flatlang programs and decodes their variables into Go values.
+//
+// Variables are decoded into the fields of a struct the same way that the fields of a map are. A field is decoded
+// into the struct field whose `flat:"name"` tag names it, or otherwise into the struct field whose name is equal to
+// it ignoring case. Struct fields tagged `flat:"-"` are skipped, and struct fields tagged `flat:"name,optional"` may
+// be left undecoded.
+//
+// Ints are converted into narrower ints, uints and floats should they not overflow them. Lists are decoded into
+// slices and arrays, and maps are decoded into maps keyed by strings and into structs. Values may be decoded into
+// any Go type that they are assignable to, such as interface{}, Constraint and Pipeline. Values that are not
+// constraints are decoded into a Constraint that constrains values to be equal to them.
+type Decoder struct {
+	// Path is the path of the program, which is reported in positions.
+	Path string
+
+	// Builtins are registered before the program is evaluated.
+	Builtins map[string]interface{}
+
+	// AllowUnknown ignores variables and map fields that no struct field is decoded from.
+	AllowUnknown bool
+
+	// AllowMissing ignores struct fields that are not tagged optional, but that have no variable or map field to be
+	// decoded from.
+	AllowMissing bool
+}
+
+// Decode evaluates the flatlang program src, and decodes its variables into the value pointed to by v. Should any
+// value fail to be decoded, Decode returns Diagnostics reporting where each failing value is declared in src.
+func (d Decoder) Decode(src []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("expected a non-nil pointer to decode into, got %T", v)
+	}
+
+	lx, err := Lex(src, d.Path)
+	if err != nil {
+		return err
+	}
+	px, err := Parse(lx)
+	if err != nil {
+		return err
+	}
+	ex := NewEval(lx)
+	if err := ex.RegisterBuiltins(d.Builtins); err != nil {
+		return err
+	}
+	if _, err := ex.Eval(px.Result); err != nil {
+		return err
+	}
+
+	dec := decoder{Decoder: d}
+	dec.value(nil, ex.sym, rv.Elem())
+	if len(dec.errs) == 0 {
+		return nil
+	}
+
+	loc := locator{lx: lx, defs: make(map[string]*ast.Assign)}
+	for _, stmt := range px.AST.Stmts {
+		if assign, ok := stmt.(*ast.Assign); ok {
+			loc.defs[assign.Name.Name] = assign
+		}
+	}
+
+	diags := make(Diagnostics, 0, len(dec.errs))
+	for _, err := range dec.errs {
+		diag := Diagnostic{Severity: SeverityError, Message: err.Error()}
+		if n := loc.locate(err.path, err.decl); n != nil {
+			diag.Pos, diag.End = lx.Position(n.Pos()), lx.Position(n.End())
+		} else {
+			diag.Pos = token.Position{Filename: lx.Position(0).Filename}
+			diag.End = diag.Pos
+		}
+		diags = append(diags, diag)
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Pos.Offset < diags[j].Pos.Offset
+	})
+	return diags
+}
+
+// convert converts val into a value of type t the same way that a Decoder decodes values.
+func convert(val interface{}, t reflect.Type) (reflect.Value, error) {
+	if val != nil && reflect.TypeOf(val).AssignableTo(t) {
+		return reflect.ValueOf(val), nil
+	}
+	rv := reflect.New(t).Elem()
+	d := decoder{converting: true}
+	d.value(nil, val, rv)
+	if len(d.errs) > 0 {
+		return reflect.Value{}, d.errs[0]
+	}
+	return rv, nil
+}
+
+type decoder struct {
+	Decoder
+	converting bool // whether values are converted into the params of builtins rather than decoded into Go values
+	errs       []decodeError
+}
+
+// decodeError is an error that occurred while decoding the value at path. Should decl be set, the error concerns
+// the declaration of the value rather than the value itself.
+type decodeError struct {
+	path []interface{}
+	decl bool
+	msg  string
+}
+
+func (e decodeError) Error() string {
+	if len(e.path) == 0 {
+		return e.msg
+	}
+	return formatPath(e.path) + ": " + e.msg
+}
+
+var (
+	constraintType = reflect.TypeOf((*Constraint)(nil)).Elem()
+	pipelineType   = reflect.TypeOf(Pipeline(nil))
+	bigIntType     = reflect.TypeOf((*big.Int)(nil))
+	bigRatType     = reflect.TypeOf((*big.Rat)(nil))
+)
+
+// value decodes val, found at path, into rv.
+func (d *decoder) value(path []interface{}, val interface{}, rv reflect.Value) {
+	if val == nil {
+		switch rv.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+			rv.Set(reflect.Zero(rv.Type()))
+			return
+		}
+	} else if reflect.TypeOf(val).AssignableTo(rv.Type()) {
+		rv.Set(reflect.ValueOf(val))
+		return
+	}
+
+	switch rv.Type() {
+	case constraintType:
+		rv.Set(reflect.ValueOf(toConstraint(val)))
+		return
+	case pipelineType:
+		if c, ok := val.(methodCall); ok {
+			rv.Set(reflect.ValueOf(Pipeline{c}))
+			return
+		}
+	case bigIntType:
+		switch val := val.(type) {
+		case int64:
+			rv.Set(reflect.ValueOf(big.NewInt(val)))
+			return
+		}
+	case bigRatType:
+		switch val := val.(type) {
+		case int64:
+			rv.Set(reflect.ValueOf(new(big.Rat).SetInt64(val)))
+			return
+		case *big.Int:
+			rv.Set(reflect.ValueOf(new(big.Rat).SetInt(val)))
+			return
+		case float64:
+			if r := new(big.Rat).SetFloat64(val); r != nil {
+				rv.Set(reflect.ValueOf(r))
+				return
+			}
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		d.value(path, val, rv.Elem())
+		return
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch val := val.(type) {
+		case int64:
+			if rv.OverflowInt(val) {
+				d.errorf(path, "%d overflows %s", val, rv.Type())
+				return
+			}
+			rv.SetInt(val)
+			return
+		case *big.Int:
+			d.errorf(path, "%s overflows %s", val, rv.Type())
+			return
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch val := val.(type) {
+		case int64:
+			if val < 0 || rv.OverflowUint(uint64(val)) {
+				d.errorf(path, "%d overflows %s", val, rv.Type())
+				return
+			}
+			rv.SetUint(uint64(val))
+			return
+		case *big.Int:
+			if val.Sign() < 0 || !val.IsUint64() || rv.OverflowUint(val.Uint64()) {
+				d.errorf(path, "%s overflows %s", val, rv.Type())
+				return
+			}
+			rv.SetUint(val.Uint64())
+			return
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch val := val.(type) {
+		case int64:
+			f = float64(val)
+		case *big.Int:
+			f, _ = new(big.Float).SetInt(val).Float64()
+		case float64:
+			f = val
+		case *big.Rat:
+			f, _ = val.Float64()
+		default:
+			d.mismatch(path, val, rv.Type())
+			return
+		}
+		if rv.OverflowFloat(f) {
+			d.errorf(path, "%s overflows %s", repr(val), rv.Type())
+			return
+		}
+		rv.SetFloat(f)
+		return
+	case reflect.Bool:
+		if val, ok := val.(bool); ok {
+			rv.SetBool(val)
+			return
+		}
+	case reflect.String:
+		if val, ok := val.(string); ok {
+			rv.SetString(val)
+			return
+		}
+	case reflect.Slice:
+		if val, ok := val.([]interface{}); ok {
+			res := reflect.MakeSlice(rv.Type(), len(val), len(val))
+			for i, elem := range val {
+				d.value(append(path[:len(path):len(path)], i), elem, res.Index(i))
+			}
+			rv.Set(res)
+			return
+		}
+	case reflect.Array:
+		if val, ok := val.([]interface{}); ok {
+			if len(val) != rv.Len() {
+				d.errorf(path, "expected a list of %d element(s) to decode into %s, got %d element(s)", rv.Len(), rv.Type(), len(val))
+				return
+			}
+			for i, elem := range val {
+				d.value(append(path[:len(path):len(path)], i), elem, rv.Index(i))
+			}
+			return
+		}
+	case reflect.Map:
+		if val, ok := val.(map[string]interface{}); ok && rv.Type().Key().Kind() == reflect.String {
+			res := reflect.MakeMapWithSize(rv.Type(), len(val))
+			for _, key := range sortedKeys(val) {
+				elem := reflect.New(rv.Type().Elem()).Elem()
+				d.value(append(path[:len(path):len(path)], key), val[key], elem)
+				res.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), elem)
+			}
+			rv.Set(res)
+			return
+		}
+	case reflect.Struct:
+		if val, ok := val.(map[string]interface{}); ok {
+			d.fields(path, val, rv)
+			return
+		}
+	}
+
+	d.mismatch(path, val, rv.Type())
+}
+
+// fields decodes the fields of the map val, found at path, into the fields of the struct rv.
+func (d *decoder) fields(path []interface{}, val map[string]interface{}, rv reflect.Value) {
+	fields := structFields(rv.Type())
+
+	decoded := make(map[string]struct{}, len(val))
+	for _, f := range fields {
+		key, exists := f.match(val)
+		if !exists {
+			if !f.optional && !d.AllowMissing {
+				d.errorf(path, "missing field %q of %s", f.name, rv.Type())
+			}
+			continue
+		}
+		decoded[key] = struct{}{}
+		d.value(append(path[:len(path):len(path)], key), val[key], rv.FieldByIndex(f.index))
+	}
+
+	if d.AllowUnknown {
+		return
+	}
+	for _, key := range sortedKeys(val) {
+		if _, exists := decoded[key]; !exists {
+			d.unknown(append(path[:len(path):len(path)], key), "unknown field in %s", rv.Type())
+		}
+	}
+}
+
+type structField struct {
+	name     string
+	index    []int
+	optional bool
+	tagged   bool
+}
+
+// match returns the key of the field in val that f is decoded from.
+func (f structField) match(val map[string]interface{}) (string, bool) {
+	if _, exists := val[f.name]; exists {
+		return f.name, true
+	}
+	if f.tagged {
+		return "", false
+	}
+	for _, key := range sortedKeys(val) {
+		if strings.EqualFold(key, f.name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// structFields returns the exported fields of the struct type t that may be decoded into.
+func structFields(t reflect.Type) []structField {
+	fields := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		f := structField{name: sf.Name, index: sf.Index}
+		if tag, ok := sf.Tag.Lookup("flat"); ok {
+			opts := strings.Split(tag, ",")
+			if opts[0] == "-" {
+				continue
+			}
+			if opts[0] != "" {
+				f.name, f.tagged = opts[0], true
+			}
+			for _, opt := range opts[1:] {
+				if opt == "optional" {
+					f.optional = true
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func (d *decoder) mismatch(path []interface{}, val interface{}, t reflect.Type) {
+	if d.converting {
+		d.errorf(path, "cannot convert %s %s to %s", typeName(val), repr(val), t)
+		return
+	}
+	d.errorf(path, "cannot decode %s %s into %s", typeName(val), repr(val), t)
+}
+
+// errorf records an error concerning the value at path.
+func (d *decoder) errorf(path []interface{}, format string, a ...interface{}) {
+	d.errs = append(d.errs, decodeError{path: path, msg: fmt.Sprintf(format, a...)})
+}
+
+// unknown records an error concerning the declaration of the variable or map field at path.
+func (d *decoder) unknown(path []interface{}, format string, a ...interface{}) {
+	d.errs = append(d.errs, decodeError{path: path, decl: true, msg: fmt.Sprintf(format, a...)})
+}
+
+// locator locates values within the source of a program by their path.
+type locator struct {
+	lx   *Lexer
+	defs map[string]*ast.Assign // last assignment to each variable
+}
+
+// locate returns the node that the value at path is written by, or should decl be set, the assignment or map field
+// that declares it. Variables holding another variable are followed to the literal assigned to it. Should the value
+// not be written by a literal, the node of the closest enclosing value is returned instead.
+func (l locator) locate(path []interface{}, decl bool) ast.Node {
+	if len(path) == 0 {
+		return nil
+	}
+	assign, exists := l.defs[path[0].(string)]
+	if !exists {
+		return nil
+	}
+
+	var (
+		declared ast.Node = assign
+		written  ast.Node = assign.Value
+		expr              = literalOf(assign.Value)
+	)
+	if expr != nil {
+		written = expr
+	}
+
+	for _, elem := range path[1:] {
+		for depth := 0; expr != nil && depth < len(l.defs); depth++ {
+			ident, ok := expr.(*ast.Ident)
+			if !ok {
+				break
+			}
+			def, exists := l.defs[ident.Name]
+			if !exists {
+				break
+			}
+			expr = literalOf(def.Value)
+		}
+
+		var next ast.Expr
+		switch lit := expr.(type) {
+		case *ast.MapLit:
+			key, _ := elem.(string)
+			for _, field := range lit.Fields {
+				if field.Key.Name == key {
+					declared, next = field, field.Value
+				}
+			}
+		case *ast.ListLit:
+			if idx, ok := elem.(int); ok && idx < len(lit.Elems) {
+				next = lit.Elems[idx]
+				declared = next
+			}
+		}
+		if next == nil {
+			return written
+		}
+		written, expr = next, next
+	}
+
+	if decl {
+		return declared
+	}
+	return written
+}
+
+// literalOf returns the expression of the pipeline p should it be a single expression without params.
+func literalOf(p *ast.Pipeline) ast.Expr {
+	if len(p.Calls) != 1 || len(p.Calls[0].Args) != 0 {
+		return nil
+	}
+	expr := p.Calls[0].Fun
+	for {
+		paren, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.X
+	}
+}
+
+func formatPath(path []interface{}) string {
+	var b strings.Builder
+	for i, elem := range path {
+		switch elem := elem.(type) {
+		case string:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(elem)
+		case int:
+			b.WriteString("[" + strconv.Itoa(elem) + "]")
+		}
+	}
+	return b.String()
+}
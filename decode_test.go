@@ -0,0 +1,109 @@
+package flatlang
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+type testDecodeConfig struct {
+	Name    string            `flat:"name"`
+	Port    uint16            `flat:"port"`
+	Ratio   float32           `flat:"ratio"`
+	Debug   bool              // matched ignoring case
+	Tags    []string          `flat:"tags"`
+	Limits  [2]int8           `flat:"limits"`
+	Labels  map[string]string `flat:"labels,optional"`
+	DB      *testDecodeDB     `flat:"db"`
+	Big     *big.Int          `flat:"big"`
+	Check   Constraint        `flat:"check"`
+	Extra   interface{}       `flat:"extra"`
+	Ignored string            `flat:"-"`
+}
+
+type testDecodeDB struct {
+	Host string `flat:"host"`
+	Pool struct {
+		Max int `flat:"max"`
+	} `flat:"pool"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	src := `
+name = 'svc';
+port = 8000 + 80;
+ratio = 0.5;
+debug = true;
+tags = ['a', 'b'];
+limits = [-1, 127];
+db = {host: 'localhost', pool: {max: 10}};
+big = 123456789012345678901234567890;
+check = !2;
+extra = [1, {a: 'b'}];
+`
+
+	var cfg testDecodeConfig
+	require.NoError(t, Unmarshal([]byte(src), &cfg))
+
+	expectedBig, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	require.Equal(t, "svc", cfg.Name)
+	require.EqualValues(t, 8080, cfg.Port)
+	require.EqualValues(t, 0.5, cfg.Ratio)
+	require.True(t, cfg.Debug)
+	require.Equal(t, []string{"a", "b"}, cfg.Tags)
+	require.Equal(t, [2]int8{-1, 127}, cfg.Limits)
+	require.Nil(t, cfg.Labels)
+	require.Equal(t, "localhost", cfg.DB.Host)
+	require.Equal(t, 10, cfg.DB.Pool.Max)
+	require.Equal(t, expectedBig, cfg.Big)
+	require.Equal(t, "!2", repr(cfg.Check))
+	require.Equal(t, []interface{}{int64(1), map[string]interface{}{"a": "b"}}, cfg.Extra)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type server struct {
+		Host string `flat:"host"`
+		Port uint8  `flat:"port"`
+	}
+	type config struct {
+		Server server   `flat:"server"`
+		Ports  []int8   `flat:"ports"`
+		Ratio  float32  `flat:"ratio"`
+		Name   string   `flat:"name"`
+		Tags   []string `flat:"tags,optional"`
+	}
+
+	src := "srv = {port: 300, user: 'root'};\nserver = srv;\nports = [1, 200];\nratio = 1e300;\nname = 3;\nextra = 1;"
+
+	var cfg config
+	err := Decoder{Path: "config.flat"}.Decode([]byte(src), &cfg)
+
+	var diags Diagnostics
+	require.True(t, errors.As(err, &diags), err)
+	require.Equal(t, []string{
+		"config.flat:1:1: srv: unknown field in flatlang.config",
+		"config.flat:1:14: server.port: 300 overflows uint8",
+		"config.flat:1:19: server.user: unknown field in flatlang.server",
+		"config.flat:2:10: server: missing field \"host\" of flatlang.server",
+		"config.flat:3:13: ports[1]: 200 overflows int8",
+		"config.flat:4:9: ratio: 1e+300 overflows float32",
+		"config.flat:5:8: name: cannot decode int 3 into string",
+		"config.flat:6:1: extra: unknown field in flatlang.config",
+	}, diagnosticStrings(diags))
+
+	cfg = config{}
+	require.NoError(t, Decoder{AllowUnknown: true, AllowMissing: true}.Decode([]byte("server = {port: 1, user: 'root'}; extra = 1;"), &cfg))
+	require.EqualValues(t, 1, cfg.Server.Port)
+
+	require.EqualError(t, Unmarshal([]byte("a = 1;"), cfg), "expected a non-nil pointer to decode into, got flatlang.config")
+}
+
+func diagnosticStrings(diags Diagnostics) []string {
+	res := make([]string, 0, len(diags))
+	for _, diag := range diags {
+		res = append(res, diag.Pos.String()+": "+diag.Message)
+	}
+	return res
+}
@@ -0,0 +1,198 @@
+package flatlang
+
+import "sort"
+
+// Dependencies returns the dependency graph of the variables declared by program n, which is parsed from the tokens
+// of lx. Each variable declared by n maps to the sorted names of the variables declared by n that its value refers
+// to. Variables that refer to no other variables declared by n map to an empty list.
+func Dependencies(lx *Lexer, n *Node) map[string][]string {
+	g := dependencies(lx, n)
+
+	res := make(map[string][]string)
+	for i, name := range g.names {
+		if name == "" {
+			continue
+		}
+		if _, exists := res[name]; !exists {
+			res[name] = []string{}
+		}
+		for _, dep := range g.deps[i] {
+			// Redeclarations of a variable that refer to its previous declaration do not depend on themselves.
+
+			if g.names[dep] == name {
+				continue
+			}
+			res[name] = append(res[name], g.names[dep])
+		}
+	}
+	for name, deps := range res {
+		sort.Strings(deps)
+		uniq := deps[:0]
+		for i, dep := range deps {
+			if i == 0 || dep != deps[i-1] {
+				uniq = append(uniq, dep)
+			}
+		}
+		res[name] = uniq
+	}
+	return res
+}
+
+// depGraph is the dependency graph of the statements of a program.
+type depGraph struct {
+	names []string // name of the variable declared by each statement, or "" should the statement declare none
+	deps  [][]int  // indices of the declarations that each statement refers to
+}
+
+// dependencies returns the dependency graph of the statements of program n. A statement that refers to a variable
+// depends on the last declaration of the variable before it. Should there be no such declaration, it depends on the
+// first declaration of the variable after it instead, unless the statement is that declaration itself, in which case
+// the statement refers to the value that the variable held before n was evaluated.
+func dependencies(lx *Lexer, n *Node) depGraph {
+	if n.Type != ProgramNode {
+		return depGraph{}
+	}
+	g := depGraph{names: make([]string, len(n.Nodes)), deps: make([][]int, len(n.Nodes))}
+
+	decls := make(map[string][]int)
+	for i, stmt := range n.Nodes {
+		if stmt.Type == VarNode {
+			g.names[i] = stmt.Nodes[0].Val(lx)
+			decls[g.names[i]] = append(decls[g.names[i]], i)
+		}
+	}
+
+	for i, stmt := range n.Nodes {
+		nodes := stmt.Nodes
+		if stmt.Type == VarNode {
+			nodes = nodes[1:]
+		}
+
+		seen := make(map[int]bool)
+		var walk func(n *Node)
+		walk = func(n *Node) {
+			switch n.Type {
+			case IdentNode:
+				indices := decls[n.Val(lx)]
+				if len(indices) == 0 {
+					return
+				}
+				j := sort.SearchInts(indices, i) - 1
+				if j < 0 {
+					j = 0
+				}
+				if dep := indices[j]; dep != i && !seen[dep] {
+					seen[dep] = true
+					g.deps[i] = append(g.deps[i], dep)
+				}
+				return
+			case MapNode:
+				for k := 1; k < len(n.Nodes); k += 2 {
+					walk(n.Nodes[k])
+				}
+				return
+			}
+			for _, node := range n.Nodes {
+				walk(node)
+			}
+		}
+		for _, node := range nodes {
+			walk(node)
+		}
+		sort.Ints(g.deps[i])
+	}
+
+	return g
+}
+
+// sort returns the order that the statements of g are evaluated in, such that each statement is evaluated after the
+// declarations it depends on. Statements are otherwise evaluated in the order that they are written in. Should the
+// declarations of g depend on each other in a cycle, the indices of the declarations in the cycle are returned
+// instead, starting and ending with the same declaration.
+func (g depGraph) sort() (order []int, cycle []int) {
+	pending := make([]int, len(g.deps))
+	dependents := make([][]int, len(g.deps))
+	for i, deps := range g.deps {
+		pending[i] = len(deps)
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+	}
+
+	var ready []int
+	for i := range g.deps {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order = make([]int, 0, len(g.deps))
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, i)
+
+		for _, dependent := range dependents[i] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				j := sort.SearchInts(ready, dependent)
+				ready = append(ready, 0)
+				copy(ready[j+1:], ready[j:])
+				ready[j] = dependent
+			}
+		}
+	}
+	if len(order) == len(g.deps) {
+		return order, nil
+	}
+
+	// Every statement left pending depends on another statement left pending. Following the dependencies of the first
+	// such statement therefore leads into a cycle.
+
+	visited := make(map[int]int)
+	i := 0
+	for pending[i] == 0 {
+		i++
+	}
+	for path := []int(nil); ; {
+		if start, exists := visited[i]; exists {
+			cycle = path[start:]
+
+			// The cycle starts with the declaration within it that is written first.
+
+			first := 0
+			for j := range cycle {
+				if cycle[j] < cycle[first] {
+					first = j
+				}
+			}
+			cycle = append(cycle[first:len(cycle):len(cycle)], cycle[:first]...)
+			return nil, append(cycle, cycle[0])
+		}
+		visited[i] = len(path)
+		path = append(path, i)
+		for _, dep := range g.deps[i] {
+			if pending[dep] > 0 {
+				i = dep
+				break
+			}
+		}
+	}
+}
+
+// order returns the order that the statements of program n are evaluated in. Should the declarations of n depend on
+// each other in a cycle, the error returned is an *EvalError wrapping a *CycleError.
+func (e *Evaluator) order(n *Node) ([]int, error) {
+	if order, exists := e.orders[n]; exists {
+		return order, nil
+	}
+	g := dependencies(e.lx, n)
+	order, cycle := g.sort()
+	if cycle == nil {
+		return order, nil
+	}
+	names := make([]string, 0, len(cycle))
+	for _, i := range cycle {
+		names = append(names, g.names[i])
+	}
+	return nil, errorAt(e.lx, &CycleError{Cycle: names}, n.Nodes[cycle[0]].Nodes[0])
+}
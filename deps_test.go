@@ -0,0 +1,133 @@
+package flatlang
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	src := "print url;\n" +
+		"url = 'http://' + host + `:${port}`;\n" +
+		"port = base + 80;\n" +
+		"host = 'localhost';\n" +
+		"base = 8000;\n" +
+		"base = base + 1;\n" +
+		"limits = {port: port, max: <=1024};"
+
+	lx, err := Lex([]byte(src), "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string][]string{
+		"url":    {"host", "port"},
+		"port":   {"base"},
+		"host":   {},
+		"base":   {},
+		"limits": {"port"},
+	}, Dependencies(lx, px.Result))
+
+	var printed []interface{}
+
+	ex := NewEval(lx)
+	require.NoError(t, ex.RegisterBuiltin("print", func(items ...interface{}) {
+		printed = append(printed, items...)
+	}))
+
+	// Declarations are evaluated before the statements that refer to them. Redeclarations refer to the previous
+	// declaration of a variable, and are otherwise referred to by the statements after them.
+
+	res, err := ex.Eval(px.Result)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"http://localhost:8080"}, printed)
+	require.Len(t, res, 7)
+
+	base, _ := ex.Lookup("base")
+	require.Equal(t, int64(8001), base)
+}
+
+func TestDependencyCycle(t *testing.T) {
+	cases := []struct {
+		src   string
+		msg   string
+		cycle []string
+	}{
+		{src: "a = b; b = c; c = a;", msg: "(input):1:1: dependency cycle: a -> b -> c -> a", cycle: []string{"a", "b", "c", "a"}},
+		{src: "x = 1; print z; y = z; z = [1, y];", msg: "(input):1:17: dependency cycle: y -> z -> y", cycle: []string{"y", "z", "y"}},
+		{src: "a = [b]; b = {a: a};", msg: "(input):1:1: dependency cycle: a -> b -> a", cycle: []string{"a", "b", "a"}},
+	}
+
+	for _, test := range cases {
+		lx, err := Lex([]byte(test.src), "")
+		require.NoError(t, err)
+		px, err := Parse(lx)
+		require.NoError(t, err)
+
+		called := false
+
+		ex := NewEval(lx)
+		require.NoError(t, ex.RegisterBuiltin("print", func(items ...interface{}) { called = true }))
+
+		_, err = ex.Eval(px.Result)
+		require.EqualError(t, err, test.msg, test.src)
+		require.False(t, called, test.src)
+
+		var ce *CycleError
+		require.True(t, errors.As(err, &ce), test.src)
+		require.Equal(t, test.cycle, ce.Cycle, test.src)
+
+		_, err = ex.Compile(px.Result)
+		require.EqualError(t, err, test.msg, test.src)
+
+		var diags Diagnostics
+		require.True(t, errors.As(ex.Check(px.Result), &diags), test.src)
+		require.Equal(t, []string{test.msg}, diagnosticStrings(diags), test.src)
+	}
+}
+
+func TestSelfReference(t *testing.T) {
+	parse := func(src string) (*Lexer, *Node) {
+		lx, err := Lex([]byte(src), "")
+		require.NoError(t, err)
+		px, err := Parse(lx)
+		require.NoError(t, err)
+		return lx, px.Result
+	}
+
+	// Declarations that refer to themselves with no declaration before them refer to the value that the variable
+	// held beforehand, such as in an interactive session.
+
+	lx, n := parse("x = 1;")
+	ex := NewEval(lx)
+	_, err := ex.Eval(n)
+	require.NoError(t, err)
+
+	lx, n = parse("x = x + 1;")
+	require.Equal(t, map[string][]string{"x": {}}, Dependencies(lx, n))
+
+	ex.SetLexer(lx)
+	_, err = ex.Eval(n)
+	require.NoError(t, err)
+
+	x, _ := ex.Lookup("x")
+	require.Equal(t, int64(2), x)
+
+	// The same holds for the variables that a compiled program is run with.
+
+	lx, n = parse("n = n + 1; m = {n: n};")
+	prog, err := Compile(lx, n)
+	require.NoError(t, err)
+
+	run, _, err := prog.Run(map[string]interface{}{"n": int64(1)})
+	require.NoError(t, err)
+
+	val, _ := run.Lookup("n")
+	require.Equal(t, int64(2), val)
+
+	val, _ = run.Lookup("m")
+	require.Equal(t, map[string]interface{}{"n": int64(2)}, val)
+
+	_, _, err = prog.Run(nil)
+	require.EqualError(t, err, "(input):1:5: unknown symbol 'n'")
+}
@@ -0,0 +1,153 @@
+package flatlang
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return ""
+}
+
+// Diagnostic describes a problem found within a range of source code.
+type Diagnostic struct {
+	Pos      token.Position // position of the first byte of the range
+	End      token.Position // position of the byte immediately after the range
+	Severity Severity
+	Expected []string // tokens that were expected, should the diagnostic be of a syntax error
+	Message  string
+}
+
+func (d Diagnostic) Error() string {
+	msg := d.Message
+	if len(d.Expected) > 0 {
+		msg += ", expecting " + strings.Join(d.Expected, " or ")
+	}
+	return fmt.Sprintf("%s: %s", d.Pos, msg)
+}
+
+// Diagnostics is a list of diagnostics. It may be returned as an error should it hold any diagnostics.
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	msgs := make([]string, 0, len(d))
+	for _, diag := range d {
+		msgs = append(msgs, diag.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// As sets target to the first diagnostic should target be a *Diagnostic, such that errors.As may find the first
+// diagnostic of a list of diagnostics.
+func (d Diagnostics) As(target interface{}) bool {
+	diag, ok := target.(*Diagnostic)
+	if !ok || len(d) == 0 {
+		return false
+	}
+	*diag = d[0]
+	return true
+}
+
+// Render renders err. Should err record the source span it occurred at, the line of source code holding the span is
+// rendered after err with the span underlined using carets. Each diagnostic in a list of diagnostics is rendered in
+// turn. Spans of eval errors are rendered against the source code that the offending node was parsed from, which
+// may be that of a lexer other than r, such as for pipelines assigned by an earlier input of an interactive session.
+func (r *Lexer) Render(err error) string {
+	var (
+		diags Diagnostics
+		diag  Diagnostic
+		ee    *EvalError
+	)
+	switch {
+	case errors.As(err, &diags):
+		msgs := make([]string, 0, len(diags))
+		for _, diag := range diags {
+			msgs = append(msgs, r.render(diag.Error(), diag.Pos, diag.End))
+		}
+		return strings.Join(msgs, "\n")
+	case errors.As(err, &diag):
+		return r.render(err.Error(), diag.Pos, diag.End)
+	case errors.As(err, &ee):
+		if ee.lx != nil {
+			r = ee.lx
+		}
+		return r.render(err.Error(), ee.Pos, ee.End)
+	}
+	return err.Error()
+}
+
+// render renders msg followed by the snippet of the range [pos, end) should there be one.
+func (r *Lexer) render(msg string, pos, end token.Position) string {
+	if snippet := r.Snippet(pos, end); snippet != "" {
+		return msg + "\n" + snippet
+	}
+	return msg
+}
+
+// Snippet returns the line of source code holding pos, followed by a line underlining the range [pos, end) using
+// carets. Should end lie on a later line, the range is underlined up until the end of the line holding pos. An empty
+// string is returned should pos or end not be positions within the source code of r.
+func (r *Lexer) Snippet(pos, end token.Position) string {
+	if pos.Filename != r.file.Name() || end.Filename != r.file.Name() {
+		return ""
+	}
+	if pos.Column < 1 || pos.Offset < pos.Column-1 || pos.Offset > len(r.Data) || end.Offset < pos.Offset || end.Offset > len(r.Data) {
+		return ""
+	}
+	start := pos.Offset - (pos.Column - 1)
+
+	line := r.Data[start:]
+	if i := bytes.IndexByte(line, '\n'); i != -1 {
+		line = line[:i]
+	}
+	line = bytes.TrimSuffix(line, []byte("\r"))
+
+	from := pos.Offset - start
+	if from > len(line) {
+		from = len(line)
+	}
+	to := len(line)
+	if end.Line == pos.Line && end.Offset-start < to {
+		to = end.Offset - start
+	}
+
+	var b strings.Builder
+	b.Write(line)
+	b.WriteByte('\n')
+
+	// Tabs are kept so that the carets line up with the span should the line be indented using tabs.
+
+	for _, c := range string(line[:from]) {
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+
+	n := 1
+	if to > from {
+		n = utf8.RuneCount(line[from:to])
+	}
+	b.WriteString(strings.Repeat("^", n))
+
+	return b.String()
+}
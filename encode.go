@@ -0,0 +1,220 @@
+package flatlang
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Marshal returns a flatlang program that assigns each field of the struct or map v to a variable. The program is
+// laid out by Format, and evaluates back into the values that v holds.
+//
+// Struct fields are named the same way that Decoder names them: struct fields tagged `flat:"-"` are skipped, and
+// struct fields tagged `flat:"name,optional"` are skipped should they hold their zero value. Maps must be keyed by
+// strings, and their keys are written in sorted order. Slices and arrays are written as lists, and structs and maps
+// nested within v are written as maps.
+//
+// Map keys and variable names must be identifiers. Nil pointers, nil interfaces and floats that are NaN or infinite
+// have no flatlang representation, and fail Marshal with an error reporting the path to them within v.
+func Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			break
+		}
+		rv = rv.Elem()
+	}
+
+	var (
+		e     encoder
+		names []string
+		vals  []reflect.Value
+	)
+
+	switch {
+	case rv.Kind() == reflect.Struct:
+		for _, f := range structFields(rv.Type()) {
+			val := rv.FieldByIndex(f.index)
+			if f.optional && val.IsZero() {
+				continue
+			}
+			names, vals = append(names, f.name), append(vals, val)
+		}
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		names = mapKeys(rv)
+		for _, name := range names {
+			vals = append(vals, rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())))
+		}
+	default:
+		return nil, fmt.Errorf("expected a struct or a map keyed by strings to marshal, got %T", v)
+	}
+
+	for i, name := range names {
+		if !isIdent(name) {
+			return nil, fmt.Errorf("variable name %q is not an identifier", name)
+		}
+		e.buf.WriteString(name)
+		e.buf.WriteString(" = ")
+		if err := e.value([]interface{}{name}, vals[i]); err != nil {
+			return nil, err
+		}
+		e.buf.WriteString(";\n")
+	}
+
+	return Format(e.buf.Bytes())
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+// value writes rv, found at path, as a flatlang expression.
+func (e *encoder) value(path []interface{}, rv reflect.Value) error {
+	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return fmt.Errorf("%s: nil has no flatlang representation", formatPath(path))
+		}
+	}
+
+	switch val := rv.Interface().(type) {
+	case Constraint:
+		e.buf.WriteString(val.String())
+		return nil
+	case Pipeline:
+		e.buf.WriteString(val.String())
+		return nil
+	case *big.Int:
+		e.buf.WriteString(val.String())
+		return nil
+	case *big.Rat:
+		e.buf.WriteString(formatSourceFloat(formatDecimal(val)))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return e.value(path, rv.Elem())
+	case reflect.Bool:
+		e.buf.WriteString(strconv.FormatBool(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("%s: %v has no flatlang representation", formatPath(path), f)
+		}
+		e.buf.WriteString(formatSourceFloat(strconv.FormatFloat(f, 'g', -1, rv.Type().Bits())))
+	case reflect.String:
+		e.buf.WriteString(quote(rv.String()))
+	case reflect.Slice, reflect.Array:
+		e.buf.WriteByte('[')
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				e.buf.WriteString(", ")
+			}
+			if err := e.value(append(path[:len(path):len(path)], i), rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: %s is not keyed by strings", formatPath(path), rv.Type())
+		}
+		e.buf.WriteByte('{')
+		for i, key := range mapKeys(rv) {
+			if i > 0 {
+				e.buf.WriteString(", ")
+			}
+			if err := e.field(path, key, rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+	case reflect.Struct:
+		e.buf.WriteByte('{')
+		first := true
+		for _, f := range structFields(rv.Type()) {
+			val := rv.FieldByIndex(f.index)
+			if f.optional && val.IsZero() {
+				continue
+			}
+			if !first {
+				e.buf.WriteString(", ")
+			}
+			first = false
+			if err := e.field(path, f.name, val); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+	default:
+		return fmt.Errorf("%s: %s has no flatlang representation", formatPath(path), rv.Type())
+	}
+	return nil
+}
+
+// field writes the map field key holding rv, where the map is found at path.
+func (e *encoder) field(path []interface{}, key string, rv reflect.Value) error {
+	path = append(path[:len(path):len(path)], key)
+	if !isIdent(key) {
+		return fmt.Errorf("%s: key %q is not an identifier", formatPath(path), key)
+	}
+	e.buf.WriteString(key)
+	e.buf.WriteString(": ")
+	return e.value(path, rv)
+}
+
+// mapKeys returns the keys of the map rv, which is keyed by strings, in sorted order.
+func mapKeys(rv reflect.Value) []string {
+	keys := make([]string, 0, rv.Len())
+	for _, key := range rv.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isIdent returns true if s would be lexed as an identifier.
+func isIdent(s string) bool {
+	if s == "" || s == "true" || s == "false" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// quote returns s as a string literal. Strings are single-quoted, unless they hold a single quote.
+func quote(s string) string {
+	q := strconv.Quote(s)
+	if strings.ContainsRune(s, '\'') {
+		return q
+	}
+	return "'" + q[1:len(q)-1] + "'"
+}
+
+// formatSourceFloat ensures that the formatted float s has a decimal point before its exponent, such that it is
+// lexed as a float.
+func formatSourceFloat(s string) string {
+	if strings.ContainsRune(s, '.') {
+		return s
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		return s[:i] + ".0" + s[i:]
+	}
+	return s + ".0"
+}
@@ -0,0 +1,108 @@
+package flatlang
+
+import (
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	expectedBig, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	cfg := testDecodeConfig{
+		Name:   "it's \"svc\"\n",
+		Port:   8080,
+		Ratio:  1e-7,
+		Debug:  true,
+		Tags:   []string{"a", "b"},
+		Limits: [2]int8{-1, 127},
+		DB:     &testDecodeDB{Host: "localhost"},
+		Big:    expectedBig,
+		Extra:  []interface{}{int64(1), map[string]interface{}{"b": 2.0, "a": "x"}},
+	}
+	cfg.DB.Pool.Max = 10
+	require.NoError(t, Decoder{AllowMissing: true}.Decode([]byte("check = >=0 & <=1024;"), &cfg))
+
+	src, err := Marshal(&cfg)
+	require.NoError(t, err)
+	require.Equal(t, `name = "it's \"svc\"\n";
+port = 8080;
+ratio = 1.0e-07;
+Debug = true;
+tags = ['a', 'b'];
+limits = [-1, 127];
+db = {host: 'localhost', pool: {max: 10}};
+big = 123456789012345678901234567890;
+check = >=0 & <=1024;
+extra = [1, {a: 'x', b: 2.0}];
+`, string(src))
+
+	var decoded testDecodeConfig
+	require.NoError(t, Unmarshal(src, &decoded))
+	require.Equal(t, cfg.Name, decoded.Name)
+	require.Equal(t, cfg.Ratio, decoded.Ratio)
+	require.Equal(t, cfg.Limits, decoded.Limits)
+	require.Equal(t, cfg.DB, decoded.DB)
+	require.Equal(t, cfg.Big, decoded.Big)
+	require.Equal(t, repr(cfg.Check), repr(decoded.Check))
+	require.Equal(t, cfg.Extra, decoded.Extra)
+
+	cfg.Labels = map[string]string{"env": "prod"}
+	src, err = Marshal(cfg)
+	require.NoError(t, err)
+	require.Contains(t, string(src), "labels = {env: 'prod'};\n")
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	vars := map[string]interface{}{
+		"i":    int64(-42),
+		"f":    -1.5,
+		"huge": 1e300,
+		"s":    "tab\there ${x}",
+		"b":    false,
+		"list": []interface{}{},
+		"map":  map[string]interface{}{"nested": []interface{}{map[string]interface{}{}}},
+	}
+
+	src, err := Marshal(vars)
+	require.NoError(t, err)
+
+	lx, err := Lex(src, "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+	ex := NewEval(lx)
+	_, err = ex.Eval(px.Result)
+	require.NoError(t, err)
+
+	for name, expected := range vars {
+		val, exists := ex.Lookup(name)
+		require.True(t, exists, name)
+		require.Equal(t, expected, val, name)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	type server struct {
+		Addrs map[string]interface{} `flat:"addrs"`
+	}
+
+	cases := []struct {
+		val interface{}
+		err string
+	}{
+		{val: []int{1}, err: "expected a struct or a map keyed by strings to marshal, got []int"},
+		{val: map[string]int{"not ident": 1}, err: "variable name \"not ident\" is not an identifier"},
+		{val: map[string]interface{}{"a": []interface{}{nil}}, err: "a[0]: nil has no flatlang representation"},
+		{val: map[string]float64{"a": math.NaN()}, err: "a: NaN has no flatlang representation"},
+		{val: map[string]interface{}{"srv": server{Addrs: map[string]interface{}{"true": 1}}}, err: "srv.addrs.true: key \"true\" is not an identifier"},
+		{val: map[string]interface{}{"a": map[int]int{}}, err: "a: map[int]int is not keyed by strings"},
+		{val: map[string]interface{}{"a": make(chan int)}, err: "a: chan int has no flatlang representation"},
+	}
+
+	for _, test := range cases {
+		_, err := Marshal(test.val)
+		require.EqualError(t, err, test.err)
+	}
+}
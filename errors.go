@@ -0,0 +1,117 @@
+package flatlang
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// Span is the source span that an error occurred at. Errors that occur while evaluating a program record the span
+// of the node that caused them, which is left zero should the node not be known, such as when a pipeline is run
+// without having been parsed from source code.
+type Span struct {
+	Pos token.Position // position of the first byte of the span
+	End token.Position // position of the byte immediately after the span
+}
+
+func (s *Span) span() *Span { return s }
+
+// spanned is implemented by errors that record the span that they occurred at.
+type spanned interface {
+	error
+	span() *Span
+}
+
+// SyntaxError is returned by Lex and Parse should source code be malformed. It records the span of the first
+// diagnostic, and unwraps into the diagnostics that describe each problem found.
+type SyntaxError struct {
+	Span
+	Diagnostics Diagnostics
+}
+
+func (e *SyntaxError) Error() string { return e.Diagnostics.Error() }
+
+func (e *SyntaxError) Unwrap() error { return e.Diagnostics }
+
+// newSyntaxError returns err as a *SyntaxError should err be a diagnostic or a list of diagnostics.
+func newSyntaxError(err error) error {
+	var diags Diagnostics
+	switch err := err.(type) {
+	case Diagnostic:
+		diags = Diagnostics{err}
+	case Diagnostics:
+		diags = err
+	default:
+		return err
+	}
+	if len(diags) == 0 {
+		return nil
+	}
+	return &SyntaxError{Span: Span{Pos: diags[0].Pos, End: diags[0].End}, Diagnostics: diags}
+}
+
+// UnknownSymbolError is reported should a program refer to a variable or builtin that does not exist.
+type UnknownSymbolError struct {
+	Span
+	Name string
+}
+
+func (e *UnknownSymbolError) Error() string {
+	return fmt.Sprintf("unknown symbol '%v'", e.Name)
+}
+
+// ArityError is reported should a builtin be called with too few or too many args.
+type ArityError struct {
+	Span
+	Builtin  string
+	Want     int  // number of args that the builtin takes, or the least number of args should it be variadic
+	Variadic bool // whether the builtin takes any number of args past Want
+	Got      int  // number of args that the builtin was called with
+}
+
+func (e *ArityError) Error() string {
+	if e.Variadic {
+		return fmt.Sprintf("%s: expected at least %d param(s), got %d param(s)", e.Builtin, e.Want, e.Got)
+	}
+	return fmt.Sprintf("%s: expected exactly %d param(s), got %d param(s)", e.Builtin, e.Want, e.Got)
+}
+
+// ArgTypeError is reported should an arg that a builtin is called with not be convertible into the type of its
+// param. Its span is that of the offending arg.
+type ArgTypeError struct {
+	Span
+	Builtin string
+	Index   int          // index of the arg
+	Type    reflect.Type // type of the param
+	Value   interface{}  // value of the arg
+	Err     error        // reason that the arg is not convertible
+}
+
+func (e *ArgTypeError) Error() string {
+	return fmt.Sprintf("%s: arg %d: %v", e.Builtin, e.Index, e.Err)
+}
+
+func (e *ArgTypeError) Unwrap() error { return e.Err }
+
+// CycleError is reported should the declarations of variables depend on each other in a cycle, as programs may not
+// be recursive. Its span is that of the name of the first declaration in the cycle.
+type CycleError struct {
+	Span
+	Cycle []string // names of the variables in the cycle, starting and ending with the same variable
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// BuiltinError wraps an error returned by a builtin, or a *PanicError should the builtin have panicked.
+type BuiltinError struct {
+	Span
+	Builtin string
+	Err     error
+}
+
+func (e *BuiltinError) Error() string { return e.Err.Error() }
+
+func (e *BuiltinError) Unwrap() error { return e.Err }
@@ -0,0 +1,89 @@
+package flatlang
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Lex([]byte("x = 'abc;"), "")
+
+	var se *SyntaxError
+	require.True(t, errors.As(err, &se))
+	require.Equal(t, "(input):1:5", se.Pos.String())
+	require.Len(t, se.Diagnostics, 1)
+
+	var diag Diagnostic
+	require.True(t, errors.As(err, &diag))
+	require.Equal(t, se.Diagnostics[0], diag)
+
+	lx, err := Lex([]byte("x = ;\ny = ;"), "")
+	require.NoError(t, err)
+	_, err = Parse(lx)
+	require.True(t, errors.As(err, &se))
+	require.Equal(t, "(input):1:5", se.Pos.String())
+	require.Len(t, se.Diagnostics, 2)
+}
+
+func TestEvalErrorTypes(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	eval := func(src string) error {
+		lx, err := Lex([]byte(src), "")
+		require.NoError(t, err, src)
+		px, err := Parse(lx)
+		require.NoError(t, err, src)
+
+		ex := NewEval(lx)
+		require.NoError(t, ex.RegisterBuiltins(map[string]interface{}{
+			"port": func(port uint16) uint16 { return port },
+			"join": func(sep string, elems ...string) {},
+			"fail": func() error { return errFailed },
+			"oops": func() { panic("oops") },
+		}))
+		_, err = ex.Eval(px.Result)
+		require.Error(t, err, src)
+		return err
+	}
+
+	var us *UnknownSymbolError
+	require.True(t, errors.As(eval("x = 1;\ny = missing;"), &us))
+	require.Equal(t, "missing", us.Name)
+	require.Equal(t, "(input):2:5", us.Pos.String())
+	require.Equal(t, "(input):2:12", us.End.String())
+
+	var ae *ArityError
+	require.True(t, errors.As(eval("x = port 1 2;"), &ae))
+	require.Equal(t, ArityError{Span: ae.Span, Builtin: "port", Want: 1, Got: 2}, *ae)
+	require.Equal(t, "port: expected exactly 1 param(s), got 2 param(s)", ae.Error())
+	require.Equal(t, "(input):1:5", ae.Pos.String())
+
+	require.True(t, errors.As(eval("join;"), &ae))
+	require.True(t, ae.Variadic)
+	require.Equal(t, "join: expected at least 1 param(s), got 0 param(s)", ae.Error())
+
+	var ate *ArgTypeError
+	err := eval("join ',' 'a' 1;")
+	require.True(t, errors.As(err, &ate))
+	require.Equal(t, "join", ate.Builtin)
+	require.Equal(t, 2, ate.Index)
+	require.Equal(t, "string", ate.Type.String())
+	require.Equal(t, int64(1), ate.Value)
+	require.Equal(t, "(input):1:14", ate.Pos.String())
+	require.EqualError(t, err, "(input):1:14: failed to call method \"join\": join: arg 2: cannot convert int 1 to string")
+
+	var be *BuiltinError
+	err = eval("x = 1;\nfail;")
+	require.True(t, errors.As(err, &be))
+	require.True(t, errors.Is(err, errFailed))
+	require.Equal(t, "fail", be.Builtin)
+	require.Equal(t, "(input):2:1", be.Pos.String())
+
+	var pe *PanicError
+	err = eval("oops;")
+	require.True(t, errors.As(err, &be))
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, "oops", be.Builtin)
+	require.Equal(t, "oops", pe.Value)
+}
@@ -1,152 +1,360 @@
 package flatlang
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/davecgh/go-spew/spew"
+	"go/token"
+	"math"
+	"math/big"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type Evaluator struct {
+	// Decimals evaluates float literals into *big.Rat values that exactly represent their decimal value, rather than
+	// into float64 values.
+	Decimals bool
+
+	// NonFinite decides how floats that are NaN or infinite produced by arithmetic are treated.
+	NonFinite NonFinitePolicy
+
+	// Limits bounds the resources that each call to Eval, EvalContext, Run and RunContext may consume.
+	Limits Limits
+
+	run      *runState // state of the current evaluation, or nil
 	lx       *Lexer
 	sym      map[string]interface{}
-	builtins map[string]reflect.Value
+	builtins map[string]Builtin
+	shared   bool                  // builtins are shared with a program, and are copied before being modified
+	consts   map[*Node]interface{} // values of literals evaluated while compiling a program
+	orders   map[*Node][]int       // order that the statements of programs compiled beforehand are evaluated in
 }
 
 func Eval(lx *Lexer, n *Node) (interface{}, error) {
 	e := NewEval(lx)
-	res, err := e.Eval(n)
-	if err != nil {
-		return nil, err
-	}
-	if len(e.sym) > 0 {
-		spew.Dump(e.sym)
-	}
-	return res, nil
+	return e.Eval(n)
 }
 
 func NewEval(lx *Lexer) *Evaluator {
 	return &Evaluator{
 		lx:       lx,
 		sym:      make(map[string]interface{}),
-		builtins: make(map[string]reflect.Value),
+		builtins: make(map[string]Builtin),
 	}
 }
 
-var errType = reflect.TypeOf((*error)(nil)).Elem()
+var (
+	errType  = reflect.TypeOf((*error)(nil)).Elem()
+	pipeType = reflect.TypeOf((*Pipe)(nil))
+)
 
-func (e *Evaluator) RegisterBuiltin(name string, fn interface{}) error {
-	v := reflect.ValueOf(fn)
-	if v.Kind() != reflect.Func {
-		return fmt.Errorf("%q is not a func", name)
-	}
+var errStrayValue = errors.New("multiple values may not exist in a single statement unless they serve as parameters for a a method call")
 
-	t := v.Type()
+// Pipe holds the value that is threaded through each call of a pipeline. Builtins that declare a *Pipe as their
+// first parameter are handed the pipe of the pipeline they are called from, and may read and replace its value.
+type Pipe struct {
+	Value interface{}
+}
 
-	if t.NumOut() > 1 {
-		return fmt.Errorf("methods may only return an error at most")
+// Pipeline is a chain of method calls linked together using pipe syntax ('>').
+type Pipeline []methodCall
+
+// SetLexer sets the lexer of the source code that nodes evaluated afterwards are parsed from. Variables assigned
+// beforehand are kept, allowing for a program to be evaluated piece by piece.
+func (e *Evaluator) SetLexer(lx *Lexer) {
+	e.lx = lx
+}
+
+// Vars returns the names of all variables in sorted order.
+func (e *Evaluator) Vars() []string {
+	names := make([]string, 0, len(e.sym))
+	for name := range e.sym {
+		names = append(names, name)
 	}
-	if t.NumOut() == 1 && !t.Out(0).Implements(errType) {
-		return fmt.Errorf("return val of method is expected to be an error, but got %v", t.Out(0))
+	sort.Strings(names)
+	return names
+}
+
+// String returns the flatlang source of p.
+func (p Pipeline) String() string {
+	calls := make([]string, 0, len(p))
+	for _, c := range p {
+		call := c.name
+		for _, param := range c.params {
+			call += " " + repr(param)
+		}
+		calls = append(calls, call)
 	}
+	return strings.Join(calls, " > ")
+}
 
-	e.builtins[name] = v
-	return nil
+// Lookup returns the value of the variable sym.
+func (e *Evaluator) Lookup(sym string) (interface{}, bool) {
+	val, recorded := e.sym[sym]
+	return val, recorded
 }
 
-func (e *Evaluator) dispatch(name string, params ...interface{}) error {
-	v, exists := e.builtins[name]
-	if !exists {
-		return fmt.Errorf("method %q not registered", name)
+// Run calls each method in pipeline p in order, threading a pipe holding input through each call. It returns the
+// value held by the pipe after the last call.
+func (e *Evaluator) Run(p Pipeline, input interface{}) (interface{}, error) {
+	if e.run == nil {
+		return e.RunContext(context.Background(), p, input)
 	}
 
-	t := v.Type()
+	pipe := &Pipe{Value: input}
+	for _, c := range p {
+		if _, err := e.dispatch(c.name, pipe, c.params...); err != nil {
+			return nil, c.fail(err)
+		}
+	}
+	return pipe.Value, nil
+}
 
-	if t.IsVariadic() {
-		if len(params) < t.NumIn()-1 {
-			return fmt.Errorf("%s: expected at least %d param(s), got %d param(s)", name, t.NumIn()-1, len(params))
+// RegisterBuiltin registers fn as the builtin name. fn is either a Builtin, or a Go func that is wrapped by
+// FuncBuiltin.
+func (e *Evaluator) RegisterBuiltin(name string, fn interface{}) error {
+	b, ok := fn.(Builtin)
+	if ok {
+		if err := checkBuiltinType(b.Type()); err != nil {
+			return fmt.Errorf("%q: %w", name, err)
 		}
 	} else {
-		if len(params) != t.NumIn() {
-			return fmt.Errorf("%s: expected exactly %d param(s), got %d param(s)", name, t.NumIn()-1, len(params))
+		if reflect.ValueOf(fn).Kind() != reflect.Func {
+			return fmt.Errorf("%q is not a func", name)
+		}
+		var err error
+		if b, err = FuncBuiltin(fn); err != nil {
+			return err
 		}
 	}
 
-	var pvs []reflect.Value
+	if e.shared {
+		builtins := make(map[string]Builtin, len(e.builtins)+1)
+		for name, b := range e.builtins {
+			builtins[name] = b
+		}
+		e.builtins, e.shared = builtins, false
+	}
 
-	if len(params) > 0 {
-		pvs = make([]reflect.Value, 0, len(params))
+	e.builtins[name] = b
+	return nil
+}
 
-		if t.IsVariadic() {
-			i := 0
+// Builtins returns the names of all registered builtins in sorted order.
+func (e *Evaluator) Builtins() []string {
+	names := make([]string, 0, len(e.builtins))
+	for name := range e.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-			for ; i < t.NumIn()-1; i++ {
-				pv, it := reflect.ValueOf(params[i]), t.In(i)
-				if !pv.Type().AssignableTo(it) {
-					return fmt.Errorf("%s: arg %d (%v) is not assignable to %v", name, i, pv.Type(), it)
-				}
-				pvs = append(pvs, pv)
-			}
+// BuiltinType returns the Go func type of the builtin name.
+func (e *Evaluator) BuiltinType(name string) (reflect.Type, bool) {
+	b, exists := e.builtins[name]
+	if !exists {
+		return nil, false
+	}
+	return b.Type(), true
+}
 
-			vt := t.In(t.NumIn() - 1).Elem()
+// dispatch calls the method name with params. If the method returns a value, the value is returned and replaces the
+// value held by pipe.
+func (e *Evaluator) dispatch(name string, pipe *Pipe, params ...interface{}) (interface{}, error) {
+	b, exists := e.builtins[name]
+	if !exists {
+		return nil, &UnknownSymbolError{Name: name}
+	}
 
-			for ; i < len(params); i++ {
-				pv := reflect.ValueOf(params[i])
-				if !pv.Type().AssignableTo(vt) {
-					return fmt.Errorf("%s: var arg %d (%v) is not assignable to %v", name, i, pv.Type(), vt)
-				}
-				pvs = append(pvs, pv)
-			}
-		} else {
-			for i := 0; i < t.NumIn(); i++ {
-				pv, it := reflect.ValueOf(params[i]), t.In(i)
-				if !pv.Type().AssignableTo(it) {
-					return fmt.Errorf("%s: arg %d (%v) is not assignable to %v", name, i, pv.Type(), it)
-				}
-				pvs = append(pvs, pv)
-			}
+	t := b.Type()
+
+	if err := checkArity(name, t, len(params)); err != nil {
+		return nil, err
+	}
+
+	if err := e.countDispatch(); err != nil {
+		return nil, err
+	}
+
+	res, err := callBuiltin(b, &CallContext{Context: e.context(), Name: name, Pipe: pipe}, params)
+	if err != nil {
+		if _, ok := err.(*ArgTypeError); ok {
+			return nil, err
 		}
+		return nil, &BuiltinError{Builtin: name, Err: err}
+	}
+
+	if !returnsValue(t) {
+		return nil, nil
+	}
+	if pipe != nil {
+		pipe.Value = res
 	}
+	return res, nil
+}
 
-	out := v.Call(pvs)
+// returnsValue reports whether the method name returns a value.
+func (e *Evaluator) returnsValue(name string) bool {
+	b, exists := e.builtins[name]
+	if !exists {
+		return false
+	}
+	return returnsValue(b.Type())
+}
 
-	if len(out) == 1 && out[0].Type().Implements(errType) && !out[0].IsNil() {
-		return out[0].Interface().(error)
+// call calls val should it be a method that returns a value, and returns the value returned. Otherwise, val is
+// returned as is.
+func (e *Evaluator) call(val interface{}) (interface{}, error) {
+	c, ok := val.(methodCall)
+	if !ok || !e.returnsValue(c.name) {
+		return val, nil
 	}
+	res, err := e.dispatch(c.name, nil, c.params...)
+	if err != nil {
+		return nil, c.fail(err)
+	}
+	return res, nil
+}
 
-	return nil
+// evalValue evaluates n, calling the method that n evaluates to should the method return a value, such that values
+// returned by methods may be used as operands.
+func (e *Evaluator) evalValue(n *Node) (interface{}, error) {
+	val, err := e.Eval(n)
+	if err != nil {
+		return nil, err
+	}
+	return e.call(val)
 }
 
 type methodCall struct {
 	name   string
 	params []interface{}
+	lx     *Lexer
+	nodes  []*Node // node of the method, followed by the node of each param, parsed from the tokens of lx
+}
+
+// String returns the flatlang source of c.
+func (c methodCall) String() string { return Pipeline{c}.String() }
+
+// fail returns err, which occurred while calling c, as an *EvalError spanning c. Should err be caused by a param of
+// c, the *EvalError spans the param instead.
+func (c methodCall) fail(err error) error {
+	nodes := c.nodes
+	var ae *ArgTypeError
+	if errors.As(err, &ae) && ae.Index+1 < len(c.nodes) {
+		nodes = c.nodes[ae.Index+1 : ae.Index+2]
+	}
+	return errorAt(c.lx, fmt.Errorf("failed to call method %q: %w", c.name, err), nodes...)
+}
+
+// EvalError is an error that occurred while evaluating a program. It records the source span of the node that
+// caused it.
+type EvalError struct {
+	Pos token.Position // position of the first byte of the offending node
+	End token.Position // position of the byte immediately after the offending node
+	Err error
+
+	lx *Lexer // lexer of the source code that the offending node was parsed from
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
 }
 
+func (e *EvalError) Unwrap() error { return e.Err }
+
+// errorAt returns err as an *EvalError spanning nodes parsed from the tokens of lx. Should err already wrap an
+// *EvalError, which records the span of a more deeply nested node that caused it, the wrapped *EvalError is returned
+// instead. Otherwise, the span of the first error wrapped by err that records a span is set to the span of nodes.
+func errorAt(lx *Lexer, err error, nodes ...*Node) error {
+	var ee *EvalError
+	if errors.As(err, &ee) {
+		return ee
+	}
+	if lx == nil {
+		return err
+	}
+	pos, end := -1, -1
+	for _, n := range nodes {
+		npos, nend := n.Span(lx)
+		if npos == -1 {
+			continue
+		}
+		if pos == -1 || npos < pos {
+			pos = npos
+		}
+		if nend > end {
+			end = nend
+		}
+	}
+	if pos == -1 {
+		pos, end = 0, 0
+	}
+
+	ee = &EvalError{Pos: lx.Position(pos), End: lx.Position(end), Err: err, lx: lx}
+
+	var se spanned
+	if errors.As(err, &se) {
+		*se.span() = Span{Pos: ee.Pos, End: ee.End}
+	}
+	return ee
+}
+
+// Eval evaluates n. Any error returned is an *EvalError.
 func (e *Evaluator) Eval(n *Node) (interface{}, error) {
+	if e.run == nil {
+		return e.EvalContext(context.Background(), n)
+	}
+	if err := e.step(); err != nil {
+		return nil, errorAt(e.lx, err, n)
+	}
+
+	res, err := e.eval(n)
+	if err != nil {
+		return nil, errorAt(e.lx, err, n)
+	}
+	return res, nil
+}
+
+func (e *Evaluator) eval(n *Node) (interface{}, error) {
+	if val, exists := e.consts[n]; exists {
+		return val, nil
+	}
+
 	switch n.Type {
 	case ProgramNode:
-		results := make([]interface{}, 0, len(n.Nodes))
-		for _, node := range n.Nodes {
-			res, err := e.Eval(node)
+		// Statements are evaluated after the declarations of the variables they refer to, such that variables may be
+		// declared in any order. Results are nonetheless returned in the order that statements are written in.
+
+		order, err := e.order(n)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make([]interface{}, len(n.Nodes))
+		for _, i := range order {
+			res, err := e.Eval(n.Nodes[i])
 			if err != nil {
 				return nil, err
 			}
 
 			switch v := res.(type) {
 			case methodCall:
-				if err := e.dispatch(v.name, v.params...); err != nil {
-					return nil, fmt.Errorf("failed to call method %q: %w", v.name, err)
+				res, err = e.Run(Pipeline{v}, nil)
+				if err != nil {
+					return nil, err
 				}
-			case []methodCall:
-				for _, c := range v {
-					if err := e.dispatch(c.name, c.params...); err != nil {
-						return nil, fmt.Errorf("failed to call method %q: %w", c.name, err)
-					}
+			case Pipeline:
+				res, err = e.Run(v, nil)
+				if err != nil {
+					return nil, err
 				}
-				results = append(results, nil)
-			default:
-				results = append(results, res)
 			}
+			results[i] = res
 		}
 		return results, nil
 	case IdentNode:
@@ -155,9 +363,12 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 			return val, nil
 		}
 		if _, exists := e.builtins[sym]; exists {
-			return methodCall{name: sym}, nil
+			return methodCall{name: sym, lx: e.lx, nodes: []*Node{n}}, nil
 		}
-		return nil, fmt.Errorf("unknown symbol '%v'", sym)
+		if c, exists := Types[sym]; exists {
+			return c, nil
+		}
+		return nil, &UnknownSymbolError{Name: sym}
 	case VarNode:
 		sym := n.Nodes[0].Val(e.lx)
 		rhs := n.Nodes[1:]
@@ -165,21 +376,36 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 		if len(rhs) == 1 {
 			res, err := e.Eval(rhs[0])
 			if err != nil {
-				return nil, fmt.Errorf("failed to eval '%v': %w", sym, err)
+				return nil, err
 			}
+
+			// Methods that return a value are called, with the value they return assigned to the variable.
+
+			if c, ok := res.(methodCall); ok {
+				res = Pipeline{c}
+			}
+			if p, ok := res.(Pipeline); ok && len(p) == 1 && e.returnsValue(p[0].name) {
+				res, err = e.Run(p, nil)
+				if err != nil {
+					return nil, err
+				}
+			}
+
 			e.sym[sym] = res
 			return nil, nil
 		}
 
-		calls := make([]methodCall, 0, len(rhs))
+		calls := make(Pipeline, 0, len(rhs))
 		for _, node := range n.Nodes[1:] {
 			res, err := e.Eval(node)
 			if err != nil {
-				return nil, fmt.Errorf("failed to eval '%v': %w", sym, err)
+				return nil, err
 			}
 
 			switch res := res.(type) {
-			case []methodCall:
+			case methodCall:
+				calls = append(calls, res)
+			case Pipeline:
 				calls = append(calls, res...)
 			default:
 				return nil, fmt.Errorf("got unknown type while eval %q's val: %w", sym, err)
@@ -194,7 +420,7 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 			return e.Eval(n.Nodes[0])
 		}
 
-		results := make([]methodCall, 0, len(n.Nodes))
+		results := make(Pipeline, 0, len(n.Nodes))
 		for i := 0; i < len(n.Nodes); i++ {
 			res, err := e.Eval(n.Nodes[i])
 			if err != nil {
@@ -204,17 +430,24 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 			switch res := res.(type) {
 			case methodCall:
 				results = append(results, res)
-			case []methodCall:
+			case Pipeline:
 				results = append(results, res...)
 			default:
 				if len(results) == 0 {
-					return nil, fmt.Errorf("multiple values may not exist in a single statement unless they serve as parameters for a a method call")
+					return nil, errorAt(e.lx, errStrayValue, n.Nodes[i])
 				}
-				results[len(results)-1].params = append(results[len(results)-1].params, res)
+
+				// Copy params before appending to them, as they may be shared with a pipeline assigned to a variable.
+
+				last := &results[len(results)-1]
+				last.params = append(last.params[:len(last.params):len(last.params)], res)
+				last.nodes = append(last.nodes[:len(last.nodes):len(last.nodes)], n.Nodes[i])
 			}
 		}
 
 		return results, nil
+	case ExprNode:
+		return e.Eval(n.Nodes[0])
 	case BoolNode:
 		val := n.Val(e.lx)
 		switch val {
@@ -236,6 +469,9 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 			if !ok {
 				return nil, fmt.Errorf("got %q while evaluating string", val)
 			}
+			if err := checkLen(len(res)+len(txt), e.Limits.MaxStringLen, "string"); err != nil {
+				return nil, err
+			}
 			res += txt
 		}
 		return res, nil
@@ -244,13 +480,21 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		val, err = e.call(val)
+		if err != nil {
+			return nil, err
+		}
 		switch val := val.(type) {
 		case string:
 			return val, nil
 		case int64:
 			return strconv.FormatInt(val, 10), nil
+		case *big.Int:
+			return val.String(), nil
 		case float64:
 			return strconv.FormatFloat(val, 'g', -1, 64), nil
+		case *big.Rat:
+			return formatRat(val), nil
 		case bool:
 			if val {
 				return "true", nil
@@ -268,12 +512,19 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 
 		return val, nil
 	case IntNode:
-		val, err := strconv.ParseInt(n.Val(e.lx), 0, 64)
+		val, err := parseInt(n.Val(e.lx))
 		if err != nil {
 			return nil, fmt.Errorf("failed to eval int: %w", err)
 		}
 		return val, nil
 	case FloatNode:
+		if e.Decimals {
+			val, err := parseRat(n.Val(e.lx))
+			if err != nil {
+				return nil, fmt.Errorf("failed to eval float: %w", err)
+			}
+			return val, nil
+		}
 		val, err := strconv.ParseFloat(n.Val(e.lx), 64)
 		if err != nil {
 			return nil, fmt.Errorf("failed to eval float: %w", err)
@@ -286,6 +537,10 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 			if err != nil {
 				return nil, err
 			}
+			val, err = e.call(val)
+			if err != nil {
+				return nil, err
+			}
 			vals = append(vals, val)
 		}
 		return vals, nil
@@ -297,149 +552,100 @@ func (e *Evaluator) Eval(n *Node) (interface{}, error) {
 			if err != nil {
 				return nil, err
 			}
+			val, err = e.call(val)
+			if err != nil {
+				return nil, err
+			}
 			vals[ident] = val
 		}
 		return vals, nil
 	case OpNode + negate:
-		rhs, err := e.Eval(n.Nodes[0])
+		rhs, err := e.evalValue(n.Nodes[0])
 		if err != nil {
-			return nil, fmt.Errorf("failed to eval rhs: %w", err)
+			return nil, err
 		}
 
-		switch rhs := rhs.(type) {
-		case int64:
-			return -rhs, nil
-		case float64:
-			return -rhs, nil
+		if res, ok := neg(rhs); ok {
+			return res, nil
 		}
 
-		return nil, fmt.Errorf("unable to negate '%v'", n.Type)
-	case OpNode + '+':
-		lhs, err := e.Eval(n.Nodes[0])
+		return nil, fmt.Errorf("unable to negate '%v'", rhs)
+	case OpNode + '+', OpNode + '-', OpNode + '*', OpNode + '/':
+		lhs, err := e.evalValue(n.Nodes[0])
 		if err != nil {
-			return nil, fmt.Errorf("failed to eval lhs: %w", err)
+			return nil, err
 		}
 
-		rhs, err := e.Eval(n.Nodes[1])
+		rhs, err := e.evalValue(n.Nodes[1])
 		if err != nil {
-			return nil, fmt.Errorf("failed to eval rhs: %w", err)
+			return nil, err
 		}
 
-		switch lhs := lhs.(type) {
-		case []interface{}:
-			switch rhs := rhs.(type) {
+		if n.Type == OpNode+'+' {
+			switch lhs := lhs.(type) {
 			case []interface{}:
-				return append(lhs, rhs...), nil
-			}
-		case string:
-			switch r := rhs.(type) {
+				switch rhs := rhs.(type) {
+				case []interface{}:
+					if err := checkLen(len(lhs)+len(rhs), e.Limits.MaxListLen, "list"); err != nil {
+						return nil, err
+					}
+					return append(lhs[:len(lhs):len(lhs)], rhs...), nil
+				}
 			case string:
-				return lhs + r, nil
-			}
-		case int64:
-			switch rhs := rhs.(type) {
-			case int64:
-				return lhs + rhs, nil
-			case float64:
-				return float64(lhs) + rhs, nil
-			}
-		case float64:
-			switch rhs := rhs.(type) {
-			case int64:
-				return lhs + float64(rhs), nil
-			case float64:
-				return lhs + rhs, nil
+				switch r := rhs.(type) {
+				case string:
+					if err := checkLen(len(lhs)+len(r), e.Limits.MaxStringLen, "string"); err != nil {
+						return nil, err
+					}
+					return lhs + r, nil
+				}
 			}
 		}
 
-		return nil, fmt.Errorf("cannot eval '%v' + '%v'", lhs, rhs)
-	case OpNode + '-':
-		lhs, err := e.Eval(n.Nodes[0])
-		if err != nil {
-			return nil, fmt.Errorf("failed to eval lhs: %w", err)
-		}
+		// Ints and exact decimals have no quotient when divided by zero, whereas floats are subject to the policy of
+		// the evaluator on floats that are NaN or infinite.
 
-		rhs, err := e.Eval(n.Nodes[1])
-		if err != nil {
-			return nil, fmt.Errorf("failed to eval rhs: %w", err)
+		exact := kindOf(lhs) != floatNumber && kindOf(rhs) != floatNumber
+		if n.Type == OpNode+'/' && isZero(rhs) && (exact || e.NonFinite == NonFiniteError) {
+			return nil, fmt.Errorf("%w: cannot eval '%v' %v '%v'", ErrDivisionByZero, lhs, n.Type, rhs)
 		}
 
-		switch lhs := lhs.(type) {
-		case int64:
-			switch rhs := rhs.(type) {
-			case int64:
-				return lhs - rhs, nil
-			case float64:
-				return float64(lhs) - rhs, nil
-			}
-		case float64:
-			switch rhs := rhs.(type) {
-			case int64:
-				return lhs - float64(rhs), nil
-			case float64:
-				return lhs - rhs, nil
+		if res, ok := arith(n.Type, lhs, rhs); ok {
+			if f, ok := res.(float64); ok && e.NonFinite == NonFiniteError && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				return nil, fmt.Errorf("%w: '%v' %v '%v' is %v", ErrNonFinite, lhs, n.Type, rhs, f)
 			}
+			return res, nil
 		}
 
-		return nil, fmt.Errorf("cannot eval '%v' - '%v'", lhs, rhs)
-	case OpNode + '*':
-		lhs, err := e.Eval(n.Nodes[0])
+		return nil, fmt.Errorf("cannot eval '%v' %v '%v'", lhs, n.Type, rhs)
+	case OpNode + '<', OpNode + '>', OpNode + lte, OpNode + gte:
+		rhs, err := e.evalValue(n.Nodes[0])
 		if err != nil {
-			return nil, fmt.Errorf("failed to eval lhs: %w", err)
+			return nil, err
 		}
-
-		rhs, err := e.Eval(n.Nodes[1])
+		return newCompareConstraint(n.Type, rhs)
+	case OpNode + '!':
+		rhs, err := e.evalValue(n.Nodes[0])
 		if err != nil {
-			return nil, fmt.Errorf("failed to eval rhs: %w", err)
-		}
-
-		switch lhs := lhs.(type) {
-		case int64:
-			switch rhs := rhs.(type) {
-			case int64:
-				return lhs * rhs, nil
-			case float64:
-				return float64(lhs) * rhs, nil
-			}
-		case float64:
-			switch rhs := rhs.(type) {
-			case int64:
-				return lhs * float64(rhs), nil
-			case float64:
-				return lhs * rhs, nil
-			}
+			return nil, err
 		}
-		return nil, fmt.Errorf("cannot eval '%v' * '%v'", lhs, rhs)
-	case OpNode + '/':
-		lhs, err := e.Eval(n.Nodes[0])
+		return newNotConstraint(rhs), nil
+	case OpNode + '&', OpNode + '|':
+		lhs, err := e.evalValue(n.Nodes[0])
 		if err != nil {
-			return nil, fmt.Errorf("failed to eval lhs: %w", err)
+			return nil, err
 		}
 
-		rhs, err := e.Eval(n.Nodes[1])
+		rhs, err := e.evalValue(n.Nodes[1])
 		if err != nil {
-			return nil, fmt.Errorf("failed to eval rhs: %w", err)
+			return nil, err
 		}
 
-		switch lhs := lhs.(type) {
-		case int64:
-			switch rhs := rhs.(type) {
-			case int64:
-				return lhs / rhs, nil
-			case float64:
-				return float64(lhs) / rhs, nil
-			}
-		case float64:
-			switch rhs := rhs.(type) {
-			case int64:
-				return lhs / float64(rhs), nil
-			case float64:
-				return lhs / rhs, nil
-			}
+		if n.Type == OpNode+'&' {
+			return newAndConstraint(lhs, rhs), nil
 		}
-		return nil, fmt.Errorf("cannot eval '%v' / '%v'", lhs, rhs)
+		return newOrConstraint(lhs, rhs), nil
 	}
 
-	spew.Dump(n)
-	panic(fmt.Sprintf("unknown node type '%v(%d)'", n.Type, n.Type))
+	return nil, fmt.Errorf("unknown node type '%v'", n.Type)
 }
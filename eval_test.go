@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/require"
+	"math"
+	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -62,3 +65,464 @@ func TestEval(t *testing.T) {
 
 	spew.Dump(res)
 }
+
+func TestPipeline(t *testing.T) {
+	src := []byte(`
+paginate
+    = default {offset: 0, limit: 1024}
+    > require {offset: >=0, limit: >=0 & <=1024}
+    > set ['limit', 'offset'];
+
+default {offset: 10, extra: true} > paginate;
+`)
+
+	lx, err := Lex(src, "")
+	require.NoError(t, err)
+
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	ex := NewEval(lx)
+
+	require.NoError(t, ex.RegisterBuiltin("default", func(p *Pipe, defaults map[string]interface{}) {
+		fields, _ := p.Value.(map[string]interface{})
+		res := make(map[string]interface{}, len(defaults)+len(fields))
+		for k, v := range defaults {
+			res[k] = v
+		}
+		for k, v := range fields {
+			res[k] = v
+		}
+		p.Value = res
+	}))
+
+	require.NoError(t, ex.RegisterBuiltin("require", func(p *Pipe, constraints map[string]interface{}) error {
+		fields, _ := p.Value.(map[string]interface{})
+		for k, c := range constraints {
+			if err := c.(Constraint).Validate(fields[k]); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+		}
+		return nil
+	}))
+
+	require.NoError(t, ex.RegisterBuiltin("set", func(p *Pipe, keys []interface{}) {
+		fields, _ := p.Value.(map[string]interface{})
+		res := make(map[string]interface{}, len(keys))
+		for _, k := range keys {
+			res[k.(string)] = fields[k.(string)]
+		}
+		p.Value = res
+	}))
+
+	res, err := ex.Eval(px.Result)
+	require.NoError(t, err)
+	require.EqualValues(t, []interface{}{nil, map[string]interface{}{"offset": int64(10), "limit": int64(1024)}}, res)
+
+	paginate, recorded := ex.Lookup("paginate")
+	require.True(t, recorded)
+	require.IsType(t, Pipeline{}, paginate)
+
+	out, err := ex.Run(paginate.(Pipeline), map[string]interface{}{"limit": int64(16)})
+	require.NoError(t, err)
+	require.EqualValues(t, map[string]interface{}{"offset": int64(0), "limit": int64(16)}, out)
+
+	_, err = ex.Run(paginate.(Pipeline), map[string]interface{}{"limit": int64(2048)})
+	require.Error(t, err)
+}
+
+func TestReturnValues(t *testing.T) {
+	src := []byte("db = 'sqlite://'; n = len [1, 2, 3]; url = concat db 'posts'; count = n + 1; " +
+		"items [n, count] `${url}?limit=${count}` {n: n}; len [1] > double;")
+
+	lx, err := Lex(src, "")
+	require.NoError(t, err)
+
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	ex := NewEval(lx)
+
+	var report []interface{}
+
+	require.NoError(t, ex.RegisterBuiltin("len", func(items []interface{}) int64 { return int64(len(items)) }))
+	require.NoError(t, ex.RegisterBuiltin("concat", func(a, b string) (string, error) { return a + b, nil }))
+	require.NoError(t, ex.RegisterBuiltin("double", func(p *Pipe) int64 { return p.Value.(int64) * 2 }))
+	require.NoError(t, ex.RegisterBuiltin("items", func(items ...interface{}) { report = append(report, items...) }))
+
+	require.Error(t, ex.RegisterBuiltin("bad", func() (int64, int64) { return 0, 0 }))
+	require.Error(t, ex.RegisterBuiltin("bad", func() (int64, int64, error) { return 0, 0, nil }))
+
+	res, err := ex.Eval(px.Result)
+	require.NoError(t, err)
+
+	require.EqualValues(t, int64(3), ex.sym["n"])
+	require.EqualValues(t, "sqlite://posts", ex.sym["url"])
+	require.EqualValues(t, int64(4), ex.sym["count"])
+
+	require.EqualValues(t, []interface{}{
+		[]interface{}{int64(3), int64(4)},
+		"sqlite://posts?limit=4",
+		map[string]interface{}{"n": int64(3)},
+	}, report)
+
+	require.EqualValues(t, int64(2), res.([]interface{})[5])
+}
+
+func TestReturnValueOperands(t *testing.T) {
+	cases := []struct {
+		src        string
+		expected   interface{}
+		constraint string
+		err        string
+	}{
+		{src: "x = two + 1;", expected: int64(3)},
+		{src: "x = 10 / two;", expected: int64(5)},
+		{src: "x = (two) * 3;", expected: int64(6)},
+		{src: "x = -two;", expected: int64(-2)},
+		{src: "x = 'a' + name;", expected: "ab"},
+		{src: "x = <=two;", constraint: "<=2"},
+		{src: "x = >=1 & <=two;", constraint: ">=1 & <=2"},
+		{src: "x = !two;", constraint: "!2"},
+		{src: "x = noop + 1;", err: "(input):1:5: cannot eval 'noop' + '1'"},
+	}
+
+	for _, test := range cases {
+		lx, err := Lex([]byte(test.src), "")
+		require.NoError(t, err)
+		px, err := Parse(lx)
+		require.NoError(t, err)
+
+		ex := NewEval(lx)
+		require.NoError(t, ex.RegisterBuiltin("two", func() int64 { return 2 }))
+		require.NoError(t, ex.RegisterBuiltin("name", func() string { return "b" }))
+		require.NoError(t, ex.RegisterBuiltin("noop", func() {}))
+
+		_, err = ex.Eval(px.Result)
+		if test.err != "" {
+			require.EqualError(t, err, test.err, test.src)
+			continue
+		}
+		require.NoError(t, err, test.src)
+
+		x, _ := ex.Lookup("x")
+		if test.constraint != "" {
+			require.Equal(t, test.constraint, x.(Constraint).String(), test.src)
+			continue
+		}
+		require.Equal(t, test.expected, x, test.src)
+	}
+}
+
+func TestEvalNumbers(t *testing.T) {
+	eval := func(src string) interface{} {
+		lx, err := Lex([]byte("("+src+");"), "")
+		require.NoError(t, err, src)
+
+		px, err := Parse(lx)
+		require.NoError(t, err, src)
+
+		res, err := NewEval(lx).Eval(px.Result)
+		require.NoError(t, err, src)
+
+		return res.([]interface{})[0]
+	}
+
+	for _, path := range []string{"testdata/int_invalid.fbs", "testdata/float.fbs"} {
+		for _, lit := range readLiterals(t, path) {
+			if !strings.HasPrefix(lit.comment, "==") {
+				continue
+			}
+			expected := strings.TrimPrefix(lit.comment, "==")
+			if i := strings.Index(expected, "("); i >= 0 {
+				expected = expected[:i]
+			}
+			require.Equal(t, eval(strings.TrimSpace(expected)), eval(lit.src), lit.src)
+		}
+	}
+
+	require.EqualValues(t, 0xBadFace, eval("0xBad_Face"))
+	require.EqualValues(t, 0600, eval("0_600"))
+	require.EqualValues(t, 0600, eval("0O600"))
+	require.EqualValues(t, 0.1249847412109375, eval("0X_1FFFP-16"))
+}
+
+func TestEvalBigNumbers(t *testing.T) {
+	eval := func(src string, decimals bool) interface{} {
+		lx, err := Lex([]byte(src+";"), "")
+		require.NoError(t, err, src)
+
+		px, err := Parse(lx)
+		require.NoError(t, err, src)
+
+		ex := NewEval(lx)
+		ex.Decimals = decimals
+
+		res, err := ex.Eval(px.Result)
+		require.NoError(t, err, src)
+
+		return res.([]interface{})[0]
+	}
+
+	for _, lit := range readLiterals(t, "testdata/int.fbs") {
+		require.Equal(t, "int", typeName(eval(lit.src, false)), lit.src)
+	}
+
+	max, ok := new(big.Int).SetString("170141183460469231731687303715884105727", 10)
+	require.True(t, ok)
+
+	require.Equal(t, max, eval("170_141183_460469_231731_687303_715884_105727", false))
+	require.Equal(t, new(big.Int).Add(max, big.NewInt(1)), eval("(170141183460469231731687303715884105727 + 1)", false))
+	require.Equal(t, new(big.Int).Neg(max), eval("(-170141183460469231731687303715884105727)", false))
+
+	require.Equal(t, new(big.Int).Lsh(big.NewInt(1), 63), eval("(9223372036854775807 + 1)", false))
+	require.Equal(t, new(big.Int).Lsh(big.NewInt(1), 64), eval("(4294967296 * 4294967296)", false))
+	require.Equal(t, int64(math.MaxInt64), eval("(9223372036854775807 + 1 - 1)", false))
+	require.Equal(t, int64(math.MinInt64), eval("(-9223372036854775807 - 1)", false))
+	require.Equal(t, int64(1), eval("(170141183460469231731687303715884105727 / 170141183460469231731687303715884105727)", false))
+
+	require.Equal(t, 0.30000000000000004, eval("(0.1 + 0.2)", false))
+	require.Equal(t, big.NewRat(3, 10), eval("(0.1 + 0.2)", true))
+	require.Equal(t, big.NewRat(1, 3), eval("(1.0 / 3)", true))
+	require.Equal(t, "0.3 1.25 170141183460469231731687303715884105728",
+		eval("`${0.1 + 0.2} ${1.25} ${170141183460469231731687303715884105727 + 1}`", true))
+
+	c := eval("(>=0.1 & <=0.3)", true).(Constraint)
+	require.Equal(t, ">=0.1 & <=0.3", c.String())
+	require.NoError(t, c.Validate(big.NewRat(3, 10)))
+	require.NoError(t, c.Validate(0.2))
+	require.Error(t, c.Validate(max))
+}
+
+func TestEvalSafeArithmetic(t *testing.T) {
+	eval := func(src string, decimals bool, policy NonFinitePolicy) (interface{}, error) {
+		lx, err := Lex([]byte(src+";"), "")
+		require.NoError(t, err, src)
+
+		px, err := Parse(lx)
+		require.NoError(t, err, src)
+
+		ex := NewEval(lx)
+		ex.Decimals = decimals
+		ex.NonFinite = policy
+
+		res, err := ex.Eval(px.Result)
+		if err != nil {
+			return nil, err
+		}
+		return res.([]interface{})[0], nil
+	}
+
+	cases := []struct {
+		src      string
+		decimals bool
+		policy   NonFinitePolicy
+		err      error
+		msg      string
+	}{
+		{src: "(1 / 0)", err: ErrDivisionByZero, msg: "(input):1:2: division by zero: cannot eval '1' / '0'"},
+		{src: "(1 / (2 - 2))", policy: NonFiniteAllow, err: ErrDivisionByZero},
+		{src: "(170141183460469231731687303715884105727 / 0)", err: ErrDivisionByZero},
+		{src: "(1.0 / 0)", decimals: true, policy: NonFiniteAllow, err: ErrDivisionByZero},
+		{src: "(1.0 / 0)", err: ErrDivisionByZero},
+		{src: "(1e308 * 10.0)", err: ErrNonFinite, msg: "(input):1:2: result is not a finite float: '1e+308' * '10' is +Inf"},
+	}
+
+	for _, test := range cases {
+		_, err := eval(test.src, test.decimals, test.policy)
+		require.True(t, errors.Is(err, test.err), "%s: %v", test.src, err)
+		if test.msg != "" {
+			require.EqualError(t, err, test.msg, test.src)
+		}
+	}
+
+	res, err := eval("(1.0 / 0)", false, NonFiniteAllow)
+	require.NoError(t, err)
+	require.True(t, math.IsInf(res.(float64), 1))
+
+	res, err = eval("(-1e308 * 10.0)", false, NonFiniteAllow)
+	require.NoError(t, err)
+	require.True(t, math.IsInf(res.(float64), -1))
+
+	_, err = NewEval(nil).Eval(NewNode(ProgramNode).N1(&Node{Type: 1 << 10}))
+	require.EqualError(t, err, "unknown node type 'NodeType(1024)'")
+}
+
+func TestEvalErrorSpan(t *testing.T) {
+	cases := []struct {
+		src     string
+		pos     string
+		snippet string
+	}{
+		{
+			src:     "a = 1;\nb = a + c;\n",
+			pos:     "(input):2:9",
+			snippet: "b = a + c;\n        ^",
+		},
+		{
+			src:     "x = 1;\n\tx + 'text';\n",
+			pos:     "(input):2:2",
+			snippet: "\tx + 'text';\n\t^^^^^^^^^^",
+		},
+		{
+			src:     "fail 1 2 > fail 3;",
+			pos:     "(input):1:1",
+			snippet: "fail 1 2 > fail 3;\n^^^^^^^^",
+		},
+		{
+			src:     "value = `${fail}`;",
+			pos:     "(input):1:12",
+			snippet: "value = `${fail}`;\n           ^^^^",
+		},
+	}
+
+	for _, test := range cases {
+		lx, err := Lex([]byte(test.src), "")
+		require.NoError(t, err)
+
+		px, err := Parse(lx)
+		require.NoError(t, err)
+
+		ex := NewEval(lx)
+		require.NoError(t, ex.RegisterBuiltin("fail", func(vals ...int64) (int64, error) {
+			return 0, errors.New("failed")
+		}))
+
+		_, err = ex.Eval(px.Result)
+		require.Error(t, err)
+
+		var ee *EvalError
+		require.True(t, errors.As(err, &ee))
+		require.Equal(t, test.pos, ee.Pos.String())
+		require.Equal(t, ee.Error()+"\n"+test.snippet, lx.Render(err))
+	}
+}
+
+func TestRenderOutOfRange(t *testing.T) {
+	long, err := Lex([]byte("xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx = 1;\nvalue = fail;"), "")
+	require.NoError(t, err)
+	short, err := Lex([]byte("value;"), "")
+	require.NoError(t, err)
+	other, err := Lex([]byte("value = fail;"), "other.fbs")
+	require.NoError(t, err)
+
+	err = &EvalError{Pos: long.Position(48), End: long.Position(52), Err: errors.New("failed")}
+	require.Equal(t, "(input):2:9: failed\nvalue = fail;\n        ^^^^", long.Render(err))
+
+	// Spans that do not lie within the source code of a lexer are rendered without a snippet.
+
+	require.Equal(t, "(input):2:9: failed", short.Render(err))
+	require.Equal(t, "(input):2:9: failed", other.Render(err))
+	require.Equal(t, "", other.Snippet(long.Position(0), long.Position(1)))
+}
+
+func TestBuiltins(t *testing.T) {
+	var buf strings.Builder
+
+	ex := NewEval(nil)
+	require.NoError(t, ex.RegisterBuiltins(Std(&buf)))
+	require.Equal(t, []string{"print", "printf"}, ex.Builtins())
+
+	typ, exists := ex.BuiltinType("printf")
+	require.True(t, exists)
+	require.Equal(t, "func(string, ...interface {})", typ.String())
+
+	_, exists = ex.BuiltinType("missing")
+	require.False(t, exists)
+
+	_, err := ex.dispatch("printf", nil, "%s=%d\n", "x", int64(1))
+	require.NoError(t, err)
+	require.Equal(t, "x=1\n", buf.String())
+}
+
+func TestDispatchConversion(t *testing.T) {
+	type page struct {
+		Offset uint   `flat:"offset"`
+		Limit  uint16 `flat:"limit,optional"`
+	}
+
+	ex := NewEval(nil)
+	require.NoError(t, ex.RegisterBuiltins(map[string]interface{}{
+		"port": func(port uint16) uint16 { return port },
+		"sum": func(vals ...int8) (res int) {
+			for _, val := range vals {
+				res += int(val)
+			}
+			return res
+		},
+		"join": func(elems []string, sep string) string { return strings.Join(elems, sep) },
+		"scale": func(ratio float32, vals [2]int) []float32 {
+			return []float32{ratio * float32(vals[0]), ratio * float32(vals[1])}
+		},
+		"page":  func(p page) page { return p },
+		"check": func(c Constraint, val int64) error { return c.Validate(val) },
+		"then":  func(p Pipeline) int { return len(p) },
+		"echo":  func(p *Pipe) {},
+	}))
+
+	cases := []struct {
+		name   string
+		params []interface{}
+		res    interface{}
+		err    string
+	}{
+		{name: "port", params: []interface{}{int64(8080)}, res: uint16(8080)},
+		{name: "port", params: []interface{}{int64(-1)}, err: "port: arg 0: -1 overflows uint16"},
+		{name: "port", params: []interface{}{"80"}, err: "port: arg 0: cannot convert string \"80\" to uint16"},
+		{name: "sum", params: []interface{}{int64(1), int64(2), int64(3)}, res: 6},
+		{name: "sum", params: []interface{}{int64(1), int64(200)}, err: "sum: arg 1: 200 overflows int8"},
+		{name: "join", params: []interface{}{[]interface{}{"a", "b"}, ","}, res: "a,b"},
+		{name: "join", params: []interface{}{[]interface{}{"a", int64(1)}, ","}, err: "join: arg 0: [1]: cannot convert int 1 to string"},
+		{name: "scale", params: []interface{}{0.5, []interface{}{int64(2), int64(4)}}, res: []float32{1, 2}},
+		{name: "page", params: []interface{}{map[string]interface{}{"offset": int64(10)}}, res: page{Offset: 10}},
+		{name: "page", params: []interface{}{map[string]interface{}{"offset": int64(10), "size": int64(1)}}, err: "page: arg 0: size: unknown field in flatlang.page"},
+		{name: "page", params: []interface{}{map[string]interface{}{}}, err: "page: arg 0: missing field \"offset\" of flatlang.page"},
+		{name: "check", params: []interface{}{int64(2), int64(2)}},
+		{name: "check", params: []interface{}{int64(2), int64(3)}, err: "3 does not satisfy 2"},
+		{name: "then", params: []interface{}{methodCall{name: "echo"}}, res: 1},
+		{name: "then", params: []interface{}{Pipeline{{name: "echo"}, {name: "echo"}}}, res: 2},
+	}
+
+	for _, test := range cases {
+		res, err := ex.dispatch(test.name, nil, test.params...)
+		if test.err != "" {
+			require.EqualError(t, err, test.err, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.res, res, test.name)
+	}
+}
+
+func TestEvalAcrossInputs(t *testing.T) {
+	ex := NewEval(nil)
+	require.NoError(t, ex.RegisterBuiltin("fail", func(val int64) error {
+		return fmt.Errorf("failed with %d", val)
+	}))
+
+	eval := func(src string) ([]interface{}, error) {
+		lx, err := Lex([]byte(src), "")
+		require.NoError(t, err)
+		px, err := Parse(lx)
+		require.NoError(t, err)
+		ex.SetLexer(lx)
+		res, err := ex.Eval(px.Result)
+		if err != nil {
+			return nil, err
+		}
+		return res.([]interface{}), nil
+	}
+
+	_, err := eval("x = 1;\nbroken = fail 2;")
+	require.NoError(t, err)
+
+	res, err := eval("x + 1;")
+	require.NoError(t, err)
+	require.EqualValues(t, []interface{}{int64(2)}, res)
+	require.Equal(t, []string{"broken", "x"}, ex.Vars())
+
+	// Errors raised by pipelines assigned in an earlier input point to where the pipeline was assigned.
+
+	_, err = eval("broken;")
+	require.EqualError(t, err, `(input):2:10: failed to call method "fail": failed with 2`)
+}
@@ -0,0 +1,493 @@
+package flatlang
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DataFormat is a data format that variables may be exported to, and that documents may be loaded from.
+type DataFormat int
+
+const (
+	JSON DataFormat = iota
+	YAML
+	TOML
+)
+
+var dataFormatNames = [...]string{
+	JSON: "json",
+	YAML: "yaml",
+	TOML: "toml",
+}
+
+func (f DataFormat) String() string { return dataFormatNames[f] }
+
+// ParseDataFormat returns the data format named name.
+func ParseDataFormat(name string) (DataFormat, error) {
+	for f, fname := range dataFormatNames {
+		if fname == name {
+			return DataFormat(f), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown data format %q", name)
+}
+
+// OpaquePolicy decides how values that have no representation as data, like constraints and pipelines, are exported.
+type OpaquePolicy int
+
+const (
+	// OpaqueError fails the export.
+	OpaqueError OpaquePolicy = iota
+	// OpaqueString exports the value as a string holding its flatlang source.
+	OpaqueString
+	// OpaqueOmit leaves the value out of the export.
+	OpaqueOmit
+)
+
+// ResultsKey is the key that the results of top-level statements are exported under.
+const ResultsKey = "results"
+
+// ExportOptions configures what is exported, and how.
+type ExportOptions struct {
+	Format DataFormat
+
+	// Vars are the names of the variables to export. All variables are exported should Vars be empty.
+	Vars []string
+
+	// Results are the values of top-level statements as returned by Eval. Should Results be non-nil, each result that
+	// is not nil is exported in order as a list under ResultsKey.
+	Results []interface{}
+
+	// Opaque decides how constraints and pipelines are exported.
+	Opaque OpaquePolicy
+}
+
+// Export exports variables into a document of the format given by opts. The keys of maps are exported in sorted
+// order, such that exporting the same variables always yields the same document.
+func (e *Evaluator) Export(opts ExportOptions) ([]byte, error) {
+	names := opts.Vars
+	if len(names) == 0 {
+		names = e.Vars()
+	}
+
+	x := exporter{format: opts.Format, opaque: opts.Opaque}
+
+	doc := make(map[string]interface{}, len(names)+1)
+	for _, name := range names {
+		val, recorded := e.sym[name]
+		if !recorded {
+			return nil, fmt.Errorf("unknown variable %q", name)
+		}
+		val, keep, err := x.value(name, val)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			doc[name] = val
+		}
+	}
+
+	if opts.Results != nil {
+		if _, exists := doc[ResultsKey]; exists {
+			return nil, fmt.Errorf("variable %q conflicts with the key that results are exported under", ResultsKey)
+		}
+		results := make([]interface{}, 0, len(opts.Results))
+		for _, res := range opts.Results {
+			if res == nil {
+				continue
+			}
+			res, keep, err := x.value(fmt.Sprintf("%s[%d]", ResultsKey, len(results)), res)
+			if err != nil {
+				return nil, err
+			}
+			if keep {
+				results = append(results, res)
+			}
+		}
+		doc[ResultsKey] = results
+	}
+
+	var buf bytes.Buffer
+	switch opts.Format {
+	case JSON:
+		writeJSON(&buf, doc, "")
+		buf.WriteByte('\n')
+	case YAML:
+		if len(doc) == 0 {
+			buf.WriteString("{}\n")
+		} else {
+			writeYAML(&buf, doc, 0)
+		}
+	case TOML:
+		writeTOMLTable(&buf, doc, "")
+	default:
+		return nil, fmt.Errorf("unknown data format %d", opts.Format)
+	}
+	return buf.Bytes(), nil
+}
+
+type exporter struct {
+	format DataFormat
+	opaque OpaquePolicy
+}
+
+// value converts val at path into a value that is representable in the export format. It reports whether val should
+// be kept in the export.
+func (x exporter) value(path string, val interface{}) (interface{}, bool, error) {
+	switch val := val.(type) {
+	case nil:
+		if x.format == TOML {
+			return nil, false, fmt.Errorf("%s: toml has no representation of nil", path)
+		}
+		return nil, true, nil
+	case bool, int64, string:
+		return val, true, nil
+	case *big.Int:
+		if x.format == TOML {
+			return nil, false, fmt.Errorf("%s: %s overflows the 64-bit integers of toml", path, val)
+		}
+		return val, true, nil
+	case float64:
+		if x.format == JSON && (math.IsNaN(val) || math.IsInf(val, 0)) {
+			return nil, false, fmt.Errorf("%s: json has no representation of %v", path, val)
+		}
+		return val, true, nil
+	case *big.Rat:
+		return val, true, nil
+	case []interface{}:
+		res := make([]interface{}, 0, len(val))
+		for i, elem := range val {
+			elem, keep, err := x.value(fmt.Sprintf("%s[%d]", path, i), elem)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				res = append(res, elem)
+			}
+		}
+		return res, true, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for key, elem := range val {
+			elem, keep, err := x.value(path+"."+key, elem)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				res[key] = elem
+			}
+		}
+		return res, true, nil
+	case Constraint, Pipeline:
+		switch x.opaque {
+		case OpaqueString:
+			return fmt.Sprint(val), true, nil
+		case OpaqueOmit:
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("%s: %s %s has no representation as data", path, typeName(val), val)
+	}
+	return nil, false, fmt.Errorf("%s: %T has no representation as data", path, val)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFloat formats f such that it is never mistaken for an integer.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// formatDecimal formats r as a decimal, such that it is never mistaken for an integer.
+func formatDecimal(r *big.Rat) string {
+	s := formatRat(r)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func writeJSON(b *bytes.Buffer, val interface{}, indent string) {
+	switch val := val.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(val))
+	case int64:
+		b.WriteString(strconv.FormatInt(val, 10))
+	case *big.Int:
+		b.WriteString(val.String())
+	case float64:
+		b.WriteString(formatFloat(val))
+	case *big.Rat:
+		b.WriteString(formatDecimal(val))
+	case string:
+		writeJSONString(b, val)
+	case []interface{}:
+		if len(val) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, elem := range val {
+			b.WriteString(indent + "  ")
+			writeJSON(b, elem, indent+"  ")
+			if i < len(val)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "]")
+	case map[string]interface{}:
+		if len(val) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for i, key := range sortedKeys(val) {
+			b.WriteString(indent + "  ")
+			writeJSONString(b, key)
+			b.WriteString(": ")
+			writeJSON(b, val[key], indent+"  ")
+			if i < len(val)-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "}")
+	}
+}
+
+func writeJSONString(b *bytes.Buffer, s string) {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	b.Truncate(b.Len() - 1) // trailing newline written by Encode
+}
+
+// writeYAML writes val as a block. The first line written is not indented, as it is expected to follow either a key,
+// or the '- ' of a list item. Every line after is indented by indent spaces.
+func writeYAML(b *bytes.Buffer, val interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch val := val.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			break
+		}
+		for i, key := range sortedKeys(val) {
+			if i > 0 {
+				b.WriteString(pad)
+			}
+			b.WriteString(yamlString(key))
+			b.WriteByte(':')
+			switch elem := val[key].(type) {
+			case map[string]interface{}:
+				if len(elem) > 0 {
+					b.WriteString("\n" + pad + "  ")
+					writeYAML(b, elem, indent+2)
+					continue
+				}
+			case []interface{}:
+				if len(elem) > 0 {
+					b.WriteString("\n" + pad)
+					writeYAML(b, elem, indent)
+					continue
+				}
+			}
+			b.WriteByte(' ')
+			writeYAML(b, val[key], indent)
+		}
+		return
+	case []interface{}:
+		if len(val) == 0 {
+			break
+		}
+		for i, elem := range val {
+			if i > 0 {
+				b.WriteString(pad)
+			}
+			b.WriteString("- ")
+			writeYAML(b, elem, indent+2)
+		}
+		return
+	}
+	b.WriteString(yamlScalar(val))
+	b.WriteByte('\n')
+}
+
+func yamlScalar(val interface{}) string {
+	switch val := val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case *big.Int:
+		return val.String()
+	case float64:
+		switch {
+		case math.IsNaN(val):
+			return ".nan"
+		case math.IsInf(val, 1):
+			return ".inf"
+		case math.IsInf(val, -1):
+			return "-.inf"
+		}
+		return formatFloat(val)
+	case *big.Rat:
+		return formatDecimal(val)
+	case string:
+		return yamlString(val)
+	case []interface{}:
+		return "[]"
+	case map[string]interface{}:
+		return "{}"
+	}
+	return ""
+}
+
+var (
+	yamlPlain    = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_ ./-]*$`)
+	yamlReserved = regexp.MustCompile(`^(?i:y|n|yes|no|on|off|true|false|null)$`)
+)
+
+// yamlString returns s as a plain scalar should it be unambiguous to do so, or as a double-quoted scalar otherwise.
+func yamlString(s string) string {
+	if yamlPlain.MatchString(s) && !yamlReserved.MatchString(s) && !strings.HasSuffix(s, " ") {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// writeTOMLTable writes the table m named name. Keys that do not hold tables are written first, followed by each
+// table held by m in its own section.
+func writeTOMLTable(b *bytes.Buffer, m map[string]interface{}, name string) {
+	keys := sortedKeys(m)
+	for _, key := range keys {
+		if _, ok := m[key].(map[string]interface{}); ok {
+			continue
+		}
+		b.WriteString(tomlKey(key))
+		b.WriteString(" = ")
+		writeTOMLInline(b, m[key])
+		b.WriteByte('\n')
+	}
+	for _, key := range keys {
+		table, ok := m[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		section := tomlKey(key)
+		if name != "" {
+			section = name + "." + section
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString("[" + section + "]\n")
+		writeTOMLTable(b, table, section)
+	}
+}
+
+func writeTOMLInline(b *bytes.Buffer, val interface{}) {
+	switch val := val.(type) {
+	case bool:
+		b.WriteString(strconv.FormatBool(val))
+	case int64:
+		b.WriteString(strconv.FormatInt(val, 10))
+	case float64:
+		switch {
+		case math.IsNaN(val):
+			b.WriteString("nan")
+		case math.IsInf(val, 1):
+			b.WriteString("inf")
+		case math.IsInf(val, -1):
+			b.WriteString("-inf")
+		default:
+			b.WriteString(formatFloat(val))
+		}
+	case *big.Rat:
+		b.WriteString(formatDecimal(val))
+	case string:
+		b.WriteString(tomlString(val))
+	case []interface{}:
+		b.WriteByte('[')
+		for i, elem := range val {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeTOMLInline(b, elem)
+		}
+		b.WriteByte(']')
+	case map[string]interface{}:
+		b.WriteByte('{')
+		for i, key := range sortedKeys(val) {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(tomlKey(key))
+			b.WriteString(" = ")
+			writeTOMLInline(b, val[key])
+		}
+		b.WriteByte('}')
+	}
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString returns s as a basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
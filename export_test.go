@@ -0,0 +1,153 @@
+package flatlang
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func evalExport(t *testing.T, src string, fns map[string]interface{}) (*Evaluator, []interface{}) {
+	lx, err := Lex([]byte(src), "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+	ex := NewEval(lx)
+	require.NoError(t, ex.RegisterBuiltins(fns))
+	res, err := ex.Eval(px.Result)
+	require.NoError(t, err)
+	return ex, res.([]interface{})
+}
+
+func TestExport(t *testing.T) {
+	src := `
+port = 8080;
+ratio = 0.5;
+name = 'svc';
+tags = ['a', 'true'];
+db = {host: 'localhost', pool: {max: 10, min: 1}, replicas: [{host: 'r1'}]};
+{ok: true};
+`
+
+	cases := []struct {
+		format   DataFormat
+		expected string
+	}{
+		{
+			format: JSON,
+			expected: `{
+  "db": {
+    "host": "localhost",
+    "pool": {
+      "max": 10,
+      "min": 1
+    },
+    "replicas": [
+      {
+        "host": "r1"
+      }
+    ]
+  },
+  "name": "svc",
+  "port": 8080,
+  "ratio": 0.5,
+  "results": [
+    {
+      "ok": true
+    }
+  ],
+  "tags": [
+    "a",
+    "true"
+  ]
+}
+`,
+		},
+		{
+			format: YAML,
+			expected: `db:
+  host: localhost
+  pool:
+    max: 10
+    min: 1
+  replicas:
+  - host: r1
+name: svc
+port: 8080
+ratio: 0.5
+results:
+- ok: true
+tags:
+- a
+- "true"
+`,
+		},
+		{
+			format: TOML,
+			expected: `name = "svc"
+port = 8080
+ratio = 0.5
+results = [{ok = true}]
+tags = ["a", "true"]
+
+[db]
+host = "localhost"
+replicas = [{host = "r1"}]
+
+[db.pool]
+max = 10
+min = 1
+`,
+		},
+	}
+
+	ex, res := evalExport(t, src, nil)
+
+	for _, test := range cases {
+		buf, err := ex.Export(ExportOptions{Format: test.format, Results: res})
+		require.NoError(t, err)
+		require.Equal(t, test.expected, string(buf), test.format)
+	}
+
+	var doc map[string]interface{}
+	buf, err := ex.Export(ExportOptions{Format: JSON})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(buf, &doc))
+	require.NotContains(t, doc, ResultsKey)
+}
+
+func TestExportOptions(t *testing.T) {
+	ex, _ := evalExport(t, `
+limit = >=0 & <=1024;
+ids = [1, !2];
+paginate = require {limit: limit} > set ['limit'];
+big = 123456789012345678901234567890;
+exact = 1;
+`, map[string]interface{}{
+		"require": func(pipe *Pipe, c map[string]interface{}) {},
+		"set":     func(pipe *Pipe, keys []interface{}) {},
+	})
+
+	buf, err := ex.Export(ExportOptions{Format: JSON, Vars: []string{"big", "exact"}})
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"big\": 123456789012345678901234567890,\n  \"exact\": 1\n}\n", string(buf))
+
+	_, err = ex.Export(ExportOptions{Format: JSON, Vars: []string{"missing"}})
+	require.EqualError(t, err, `unknown variable "missing"`)
+
+	_, err = ex.Export(ExportOptions{Format: YAML, Vars: []string{"ids"}})
+	require.EqualError(t, err, "ids[1]: constraint !2 has no representation as data")
+
+	_, err = ex.Export(ExportOptions{Format: YAML, Vars: []string{"paginate"}})
+	require.EqualError(t, err, `paginate: pipeline require {limit: >=0 & <=1024} > set ["limit"] has no representation as data`)
+
+	_, err = ex.Export(ExportOptions{Format: TOML, Vars: []string{"big"}})
+	require.EqualError(t, err, "big: 123456789012345678901234567890 overflows the 64-bit integers of toml")
+
+	buf, err = ex.Export(ExportOptions{Format: YAML, Vars: []string{"ids", "limit", "paginate"}, Opaque: OpaqueString})
+	require.NoError(t, err)
+	require.Equal(t, "ids:\n- 1\n- \"!2\"\nlimit: \">=0 & <=1024\"\npaginate: \"require {limit: >=0 & <=1024} > set [\\\"limit\\\"]\"\n", string(buf))
+
+	buf, err = ex.Export(ExportOptions{Format: TOML, Vars: []string{"ids", "limit", "paginate"}, Opaque: OpaqueOmit})
+	require.NoError(t, err)
+	require.Equal(t, "ids = [1]\n", string(buf))
+}
@@ -0,0 +1,218 @@
+package flatlang
+
+import (
+	"bytes"
+	"github.com/lithdew/flatlang/ast"
+	"strings"
+)
+
+// Format formats the flatlang program src into its canonical form.
+//
+// Each token is separated by a single space, except for those within brackets and after unary operators. Pipelines
+// with more than one stage are laid out with one stage per line, with each stage after the first indented and led by
+// '>'. Comments are kept in place, and runs of blank lines between statements are collapsed into a single blank line.
+// Comments that lie within a stage are moved onto their own line right before the stage.
+func Format(src []byte) ([]byte, error) {
+	lx, err := Lex(src, "")
+	if err != nil {
+		return nil, err
+	}
+	px, err := Parse(lx)
+	if err != nil {
+		return nil, err
+	}
+	p := printer{lx: lx, comments: lx.Comments, prev: -1}
+	p.program(px.AST)
+	return p.buf.Bytes(), nil
+}
+
+type printer struct {
+	lx       *Lexer
+	buf      bytes.Buffer
+	comments []Token // comments that have yet to be printed
+	prev     int     // offset of the byte immediately after the last statement or comment printed, or -1
+}
+
+// newlines returns the number of newlines in the source between offsets from and to.
+func (p *printer) newlines(from, to int) int {
+	if from < 0 || from >= to {
+		return 0
+	}
+	return bytes.Count(p.lx.Data[from:to], []byte("\n"))
+}
+
+// separate prints a blank line should there be at least one blank line in the source between the last statement
+// or comment printed, and the statement or comment starting at offset pos.
+func (p *printer) separate(pos int) {
+	if p.newlines(p.prev, pos) > 1 {
+		p.buf.WriteByte('\n')
+	}
+}
+
+// flush prints each comment starting before offset pos on its own line, prefixed with indent.
+func (p *printer) flush(pos int, indent string) {
+	for len(p.comments) > 0 && p.comments[0].Pos < pos {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if indent == "" {
+			p.separate(c.Pos)
+		}
+		p.buf.WriteString(indent)
+		p.buf.Write(p.lx.Data[c.Pos:c.End])
+		p.buf.WriteByte('\n')
+		p.prev = c.End
+	}
+}
+
+// trail prints each comment that starts after offset pos on the same line as pos, and before offset limit.
+func (p *printer) trail(pos, limit int) {
+	for len(p.comments) > 0 && p.comments[0].Pos >= pos && p.comments[0].Pos < limit {
+		c := p.comments[0]
+		if p.newlines(pos, c.Pos) > 0 {
+			return
+		}
+		p.comments = p.comments[1:]
+
+		p.buf.WriteByte(' ')
+		p.buf.Write(p.lx.Data[c.Pos:c.End])
+		pos = c.End
+		p.prev = c.End
+	}
+}
+
+func (p *printer) program(prog *ast.Program) {
+	for i, stmt := range prog.Stmts {
+		semi := prog.Semicolons[i]
+
+		p.flush(stmt.Pos(), "")
+		p.separate(stmt.Pos())
+
+		switch stmt := stmt.(type) {
+		case *ast.Assign:
+			p.assign(stmt)
+		case *ast.Pipeline:
+			p.pipeline(stmt, "")
+		}
+		p.buf.WriteByte(';')
+		p.prev = semi + 1
+
+		p.trail(semi+1, len(p.lx.Data))
+		p.buf.WriteByte('\n')
+	}
+	p.flush(len(p.lx.Data)+1, "")
+}
+
+func (p *printer) assign(n *ast.Assign) {
+	if len(n.Value.Calls) == 1 {
+		p.flush(n.End(), "")
+		p.buf.WriteString(n.Name.Name)
+		p.buf.WriteString(" = ")
+		p.call(n.Value.Calls[0])
+		return
+	}
+	p.flush(n.Name.End(), "")
+	p.buf.WriteString(n.Name.Name)
+	p.buf.WriteByte('\n')
+	p.pipeline(n.Value, "=")
+}
+
+// pipeline prints pipeline n. Should lead be set, the first stage is indented and led by lead.
+func (p *printer) pipeline(n *ast.Pipeline, lead string) {
+	if len(n.Calls) == 1 && lead == "" {
+		p.flush(n.End(), "")
+		p.call(n.Calls[0])
+		return
+	}
+	for i, call := range n.Calls {
+		if i > 0 {
+			p.trail(n.Calls[i-1].End(), call.Pos())
+			p.buf.WriteByte('\n')
+		}
+
+		indent, op := "\t", ">"
+		if i == 0 {
+			indent, op = "", lead
+			if lead != "" {
+				indent = "\t"
+			}
+		}
+
+		p.flush(call.End(), indent)
+		p.buf.WriteString(indent)
+		if op != "" {
+			p.buf.WriteString(op)
+			p.buf.WriteByte(' ')
+		}
+		p.call(call)
+	}
+}
+
+func (p *printer) call(n *ast.Call) {
+	p.expr(n.Fun)
+	for _, arg := range n.Args {
+		p.buf.WriteByte(' ')
+		p.expr(arg)
+	}
+}
+
+func (p *printer) expr(n ast.Expr) {
+	switch n := n.(type) {
+	case *ast.Ident:
+		p.buf.WriteString(n.Name)
+	case *ast.BasicLit:
+		p.buf.WriteString(n.Value)
+	case *ast.StringLit:
+		p.buf.WriteByte(n.Quote)
+		for _, part := range n.Parts {
+			switch part := part.(type) {
+			case *ast.Text:
+				p.buf.WriteString(part.Value)
+			case *ast.Interp:
+				p.buf.WriteString("${")
+				p.expr(part.X)
+				p.buf.WriteByte('}')
+			}
+		}
+		p.buf.WriteByte(n.Quote)
+	case *ast.ListLit:
+		p.buf.WriteByte('[')
+		for i, elem := range n.Elems {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.expr(elem)
+		}
+		p.buf.WriteByte(']')
+	case *ast.MapLit:
+		p.buf.WriteByte('{')
+		for i, field := range n.Fields {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.buf.WriteString(field.Key.Name)
+			p.buf.WriteString(": ")
+			p.expr(field.Value)
+		}
+		p.buf.WriteByte('}')
+	case *ast.ParenExpr:
+		p.buf.WriteByte('(')
+		p.expr(n.X)
+		p.buf.WriteByte(')')
+	case *ast.UnaryExpr:
+		p.buf.WriteString(n.Op)
+
+		// Separate operators that would otherwise be lexed as a different operator, like '-' followed by '-1'.
+
+		if x, ok := n.X.(*ast.UnaryExpr); ok && strings.HasPrefix(x.Op, n.Op[len(n.Op)-1:]) {
+			p.buf.WriteByte(' ')
+		}
+		p.expr(n.X)
+	case *ast.BinaryExpr:
+		p.expr(n.X)
+		p.buf.WriteByte(' ')
+		p.buf.WriteString(n.Op)
+		p.buf.WriteByte(' ')
+		p.expr(n.Y)
+	}
+}
@@ -0,0 +1,77 @@
+package flatlang
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		src      string
+		expected string
+	}{
+		{
+			src:      "x=1;   y  =  x+2 ;print   x y;",
+			expected: "x = 1;\ny = x + 2;\nprint x y;\n",
+		},
+		{
+			src:      "check [ 1,2 ,3 ] {a:1,b : `${x}!`} ( - 1 ) >=0&<=10;",
+			expected: "check [1, 2, 3] {a: 1, b: `${x}!`} (-1) >=0 & <=10;\n",
+		},
+		{
+			src:      "get '/' > a > b;\npaginate = default {limit: 1} > require {limit: <=1024};",
+			expected: "get '/'\n\t> a\n\t> b;\npaginate\n\t= default {limit: 1}\n\t> require {limit: <=1024};\n",
+		},
+		{
+			src:      "// leading\n\n\n\nx = 1; // trailing\n/* block */ y = 2;\n\n// dangling\n",
+			expected: "// leading\n\nx = 1; // trailing\n/* block */\ny = 2;\n\n// dangling\n",
+		},
+		{
+			src:      "get '/' // route\n  > a // first\n  // before b\n  > b;",
+			expected: "get '/' // route\n\t> a // first\n\t// before b\n\t> b;\n",
+		},
+		{
+			src:      "print 1 /* inside */ 2;",
+			expected: "/* inside */\nprint 1 2;\n",
+		},
+	}
+
+	for _, test := range cases {
+		res, err := Format([]byte(test.src))
+		require.NoError(t, err)
+		require.Equal(t, test.expected, string(res))
+
+		again, err := Format(res)
+		require.NoError(t, err)
+		require.Equal(t, string(res), string(again))
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/test.fbs")
+	require.NoError(t, err)
+
+	res, err := Format(src)
+	require.NoError(t, err)
+
+	again, err := Format(res)
+	require.NoError(t, err)
+	require.Equal(t, string(res), string(again))
+
+	before, err := Lex(src, "")
+	require.NoError(t, err)
+	after, err := Lex(res, "")
+	require.NoError(t, err)
+
+	require.Len(t, after.Tokens, len(before.Tokens))
+	require.Len(t, after.Comments, len(before.Comments))
+	for i := range before.Tokens {
+		require.Equal(t, before.Tokens[i].Sym, after.Tokens[i].Sym)
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	_, err := Format([]byte("x = ;"))
+	require.Error(t, err)
+}
@@ -3,10 +3,12 @@ module github.com/lithdew/flatlang
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v1.0.0
 	github.com/chzyer/logex v1.1.10 // indirect
 	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e
 	github.com/chzyer/test v0.0.0-20180213035817-a1ea475d72b1 // indirect
 	github.com/davecgh/go-spew v1.1.0
 	github.com/stretchr/testify v1.5.1
 	golang.org/x/sys v0.0.0-20200519105757-fe76b779f299 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -3,11 +3,9 @@
 
 package flatlang
 
-import "fmt"
+// line 99 "lex.rl"
 
-// line 101 "lex.rl"
-
-// line 15 "lex.go"
+// line 13 "lex.go"
 const expr_start int = 10
 const expr_first_final int = 10
 const expr_error int = 0
@@ -17,7 +15,7 @@ const expr_en_rstring int = 32
 const expr_en_qstring int = 34
 const expr_en_expr int = 10
 
-// line 104 "lex.rl"
+// line 102 "lex.rl"
 
 func lexData(data []byte, r *Lexer) (err error) {
 	var cs, act, ts, te, top int
@@ -67,6 +65,16 @@ func lexData(data []byte, r *Lexer) (err error) {
 		r.Tokens[len(r.Tokens)-1].Prev = iprev
 		return true
 	}
+	number := func() bool {
+		end, sym, msg := scanNumber(data, ts)
+		te = end
+		if msg != "" {
+			err = r.errorAt(ts, te, "%s: %s", data[ts:te], msg)
+			return false
+		}
+		tok(sym)
+		return true
+	}
 	addLines := func() {
 		for i := ts; i < te; i++ {
 			if data[i] == '\n' {
@@ -254,7 +262,10 @@ func lexData(data []byte, r *Lexer) (err error) {
 
 		p = (te) - 1
 		{
-			tok(float)
+			if !number() {
+				return
+			}
+			p = (te) - 1
 		}
 		goto st10
 	tr7:
@@ -412,7 +423,10 @@ func lexData(data []byte, r *Lexer) (err error) {
 		te = p
 		p--
 		{
-			tok(float)
+			if !number() {
+				return
+			}
+			p = (te) - 1
 		}
 		goto st10
 	tr39:
@@ -455,7 +469,10 @@ func lexData(data []byte, r *Lexer) (err error) {
 		te = p
 		p--
 		{
-			tok(int_)
+			if !number() {
+				return
+			}
+			p = (te) - 1
 		}
 		goto st10
 	tr44:
@@ -1518,7 +1535,7 @@ func lexData(data []byte, r *Lexer) (err error) {
 	} else if len(backrefs) != 0 {
 		iprev, _ := backrefs.Pop()
 		prev := r.Tokens[iprev]
-		err = fmt.Errorf("%s%s is not terminated", r.At(prev.Pos), Repr(prev.Sym))
+		err = r.errorAt(prev.Pos, prev.End, "%s is not terminated", Repr(prev.Sym))
 	}
 	return
 }
@@ -2,8 +2,6 @@
 
 package flatlang
 
-import "fmt"
-
 %%{
 
 machine expr;
@@ -78,8 +76,8 @@ Comment2 => { tokcomment(comment); addLines() };
 
 Ident    => { tok(ident) };
 Bool     => { tok(bool_)  };
-Int      => { tok(int_) };
-Float    => { tok(float) };
+Int      => { if !number() { return }; fexec te; };
+Float    => { if !number() { return }; fexec te; };
 
 "<="  => { tok(lte) };
 ">="  => { tok(gte) };
@@ -140,6 +138,16 @@ func lexData(data []byte, r *Lexer) (err error) {
 		r.Tokens[len(r.Tokens)-1].Prev = iprev
 		return true
 	}
+	number := func() bool {
+		end, sym, msg := scanNumber(data, ts)
+		te = end
+		if msg != "" {
+			err = r.errorAt(ts, te, "%s: %s", data[ts:te], msg)
+			return false
+		}
+		tok(sym)
+		return true
+	}
 	addLines := func() {
 		for i := ts; i < te; i++ {
 			if data[i] == '\n' {
@@ -155,7 +163,7 @@ func lexData(data []byte, r *Lexer) (err error) {
 	} else if len(backrefs) != 0 {
 		iprev, _ := backrefs.Pop()
 		prev := r.Tokens[iprev]
-		err = fmt.Errorf("%s%s is not terminated", r.At(prev.Pos), Repr(prev.Sym))
+		err = r.errorAt(prev.Pos, prev.End, "%s is not terminated", Repr(prev.Sym))
 	}
 	return
 }
\ No newline at end of file
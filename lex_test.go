@@ -1,8 +1,10 @@
 package flatlang
 
 import (
+	"errors"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
+	"strings"
 	"testing"
 )
 
@@ -25,3 +27,91 @@ func BenchmarkLex(b *testing.B) {
 		}
 	}
 }
+
+type literal struct {
+	src     string
+	comment string
+}
+
+// readLiterals reads the literals listed one per line in the file at path, alongside their trailing comments.
+func readLiterals(t *testing.T, path string) []literal {
+	buf, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	var literals []literal
+	for _, line := range strings.Split(string(buf), "\n") {
+		var comment string
+		if i := strings.Index(line, "//"); i >= 0 {
+			line, comment = line[:i], strings.TrimSpace(line[i+2:])
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		literals = append(literals, literal{src: line, comment: comment})
+	}
+	return literals
+}
+
+func TestLexNumbers(t *testing.T) {
+	paths := []string{"testdata/int.fbs", "testdata/int_invalid.fbs", "testdata/float.fbs", "testdata/float_invalid.fbs"}
+
+	// Outcomes of literals that are listed in the fixtures without a comment describing them. Literals that have
+	// neither are expected to be valid.
+
+	outcomes := map[string]string{
+		"0o777": "== 511",
+		"0b99":  "invalid: invalid digit '9' in binary literal",
+	}
+
+	for _, path := range paths {
+		for _, lit := range readLiterals(t, path) {
+			if lit.comment == "" {
+				lit.comment = outcomes[lit.src]
+			}
+
+			lx, err := Lex([]byte(lit.src), path)
+			if strings.HasPrefix(lit.comment, "invalid") {
+				require.Error(t, err, lit.src)
+				continue
+			}
+			require.NoError(t, err, lit.src)
+
+			if strings.HasPrefix(lit.comment, "an identifier") {
+				continue
+			}
+
+			tok := lx.Tokens[0]
+			require.Contains(t, []int{int_, float}, tok.Sym, lit.src)
+			if !strings.Contains(lit.comment, "integer subtraction") {
+				require.Len(t, lx.Tokens, 1, lit.src)
+				require.Equal(t, len(lit.src), tok.End, lit.src)
+			}
+		}
+	}
+}
+
+func TestLexNumberErrors(t *testing.T) {
+	tests := map[string]string{
+		"42_":      "(input):1:1: 42_: '_' must separate successive digits",
+		"0b2e":     "(input):1:1: 0b2e: 'e' exponent requires decimal mantissa",
+		"0x1.5e-2": "(input):1:1: 0x1.5e: hexadecimal mantissa requires a 'p' exponent",
+		"x = 0o8;": "(input):1:5: 0o8: invalid digit '8' in octal literal",
+	}
+
+	for src, expected := range tests {
+		_, err := Lex([]byte(src), "")
+		require.EqualError(t, err, expected)
+	}
+}
+
+func TestLexDiagnostic(t *testing.T) {
+	_, err := Lex([]byte("x = 1;\ny = 'text;"), "")
+	require.Error(t, err)
+
+	var diag Diagnostic
+	require.True(t, errors.As(err, &diag))
+	require.Equal(t, "(input):2:5", diag.Pos.String())
+	require.Equal(t, "(input):2:6", diag.End.String())
+	require.Equal(t, "(input):2:5: ' is not terminated", err.Error())
+}
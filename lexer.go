@@ -23,8 +23,9 @@ type Lexer struct {
 
 func Lex(data []byte, path string) (*Lexer, error) {
 	result := newLexer(path, len(data))
+	result.file.SetLinesForContent(data)
 	if err := lexData(data, result); err != nil {
-		return nil, err
+		return nil, newSyntaxError(err)
 	}
 	return result, nil
 }
@@ -44,6 +45,11 @@ func newLexer(path string, size int) *Lexer {
 	return &Lexer{file: fileset.AddFile(path, -1, size)}
 }
 
+// Position returns the position of the byte at offset.
+func (r *Lexer) Position(offset int) token.Position {
+	return r.file.Position(r.file.Pos(offset))
+}
+
 func (r *Lexer) At(offset int) string {
 	p := r.file.Position(r.file.Pos(offset))
 	return fmt.Sprintf("%s:%d:%d: ", p.Filename, p.Line, p.Column)
@@ -54,6 +60,18 @@ func (r *Lexer) Last() string {
 	return r.At(tok.Pos) + Repr(tok.Sym)
 }
 
+// Errorf returns a diagnostic of an error that occurred at the last token lexed.
 func (r *Lexer) Errorf(format string, a ...interface{}) error {
-	return fmt.Errorf("%s "+format, append([]interface{}{r.Last()}, a...))
+	tok := r.Tokens[len(r.Tokens)-1]
+	return r.errorAt(tok.Pos, tok.End, "%s "+format, append([]interface{}{Repr(tok.Sym)}, a...)...)
+}
+
+// errorAt returns a diagnostic of an error that occurred within the offsets [pos, end).
+func (r *Lexer) errorAt(pos, end int, format string, a ...interface{}) error {
+	return Diagnostic{
+		Pos:      r.Position(pos),
+		End:      r.Position(end),
+		Severity: SeverityError,
+		Message:  fmt.Sprintf(format, a...),
+	}
 }
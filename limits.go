@@ -0,0 +1,108 @@
+package flatlang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Limits bounds the resources that a single evaluation may consume, such that programs from untrusted sources may be
+// evaluated safely. Limits that are zero are not enforced.
+type Limits struct {
+	MaxSteps      int // maximum number of nodes evaluated
+	MaxDispatches int // maximum number of builtins called
+	MaxStringLen  int // maximum length in bytes of strings concatenated by '+' or built by interpolation
+	MaxListLen    int // maximum number of elements of lists concatenated by '+'
+}
+
+var (
+	// ErrStepLimit is reported once an evaluation evaluates more nodes than Limits.MaxSteps.
+	ErrStepLimit = errors.New("exceeded the maximum number of steps")
+
+	// ErrDispatchLimit is reported once an evaluation calls more builtins than Limits.MaxDispatches.
+	ErrDispatchLimit = errors.New("exceeded the maximum number of builtin calls")
+
+	// ErrSizeLimit is reported once '+' or interpolation produces a string or list longer than Limits.MaxStringLen
+	// or Limits.MaxListLen.
+	ErrSizeLimit = errors.New("exceeded the maximum size of a value")
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// runState is the state of a single evaluation.
+type runState struct {
+	ctx        context.Context
+	done       <-chan struct{}
+	steps      int
+	dispatches int
+}
+
+// EvalContext evaluates n the same way that Eval does. Evaluation stops once ctx is done, or once e.Limits are
+// exceeded. ctx is handed to builtins that are called while evaluating n. Any error returned is an *EvalError, which
+// wraps ctx.Err() should evaluation have stopped because ctx is done.
+func (e *Evaluator) EvalContext(ctx context.Context, n *Node) (interface{}, error) {
+	defer e.begin(ctx)()
+	return e.Eval(n)
+}
+
+// RunContext runs pipeline p the same way that Run does, with evaluation stopping and ctx being handed to builtins
+// the same way as with EvalContext.
+func (e *Evaluator) RunContext(ctx context.Context, p Pipeline, input interface{}) (interface{}, error) {
+	defer e.begin(ctx)()
+	return e.Run(p, input)
+}
+
+// begin begins an evaluation in ctx. It returns a func that ends the evaluation.
+func (e *Evaluator) begin(ctx context.Context) func() {
+	prev := e.run
+	e.run = &runState{ctx: ctx, done: ctx.Done()}
+	return func() { e.run = prev }
+}
+
+// step records that a node is about to be evaluated.
+func (e *Evaluator) step() error {
+	select {
+	case <-e.run.done:
+		return fmt.Errorf("evaluation stopped: %w", e.run.ctx.Err())
+	default:
+	}
+	e.run.steps++
+	if e.Limits.MaxSteps > 0 && e.run.steps > e.Limits.MaxSteps {
+		return fmt.Errorf("%w (%d)", ErrStepLimit, e.Limits.MaxSteps)
+	}
+	return nil
+}
+
+// countDispatch records that a builtin is about to be called.
+func (e *Evaluator) countDispatch() error {
+	if e.run == nil {
+		return nil
+	}
+	select {
+	case <-e.run.done:
+		return fmt.Errorf("evaluation stopped: %w", e.run.ctx.Err())
+	default:
+	}
+	e.run.dispatches++
+	if e.Limits.MaxDispatches > 0 && e.run.dispatches > e.Limits.MaxDispatches {
+		return fmt.Errorf("%w (%d)", ErrDispatchLimit, e.Limits.MaxDispatches)
+	}
+	return nil
+}
+
+// context returns the context of the current evaluation.
+func (e *Evaluator) context() context.Context {
+	if e.run == nil {
+		return context.Background()
+	}
+	return e.run.ctx
+}
+
+// checkLen checks that a string or list of length n produced by '+' does not exceed max.
+func checkLen(n, max int, kind string) error {
+	if max > 0 && n > max {
+		return fmt.Errorf("%w: %s of length %d exceeds %d", ErrSizeLimit, kind, n, max)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package flatlang
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	cases := []struct {
+		src    string
+		limits Limits
+		err    error
+		msg    string
+	}{
+		{src: "x = 1 + 2 + 3;", limits: Limits{MaxSteps: 8}},
+		{src: "x = 1 + 2 + 3;", limits: Limits{MaxSteps: 7}, err: ErrStepLimit, msg: "(input):1:13: exceeded the maximum number of steps (7)"},
+		{src: "noop; noop;", limits: Limits{MaxDispatches: 2}},
+		{src: "noop; noop; noop;", limits: Limits{MaxDispatches: 2}, err: ErrDispatchLimit, msg: "(input):1:13: failed to call method \"noop\": exceeded the maximum number of builtin calls (2)"},
+		{src: "x = 'ab' + 'c';", limits: Limits{MaxStringLen: 3}},
+		{src: "x = 'ab' + 'cd';", limits: Limits{MaxStringLen: 3}, err: ErrSizeLimit, msg: "(input):1:5: exceeded the maximum size of a value: string of length 4 exceeds 3"},
+		{src: "v = 'abcd'; x = `${v}${v}`;", limits: Limits{MaxStringLen: 8}},
+		{src: "v = 'abcd'; v = `${v}${v}`; v = `${v}${v}`;", limits: Limits{MaxStringLen: 8}, err: ErrSizeLimit, msg: "(input):1:33: exceeded the maximum size of a value: string of length 16 exceeds 8"},
+		{src: "x = [1] + [2] + [3];", limits: Limits{MaxListLen: 2}, err: ErrSizeLimit, msg: "(input):1:5: exceeded the maximum size of a value: list of length 3 exceeds 2"},
+	}
+
+	for _, test := range cases {
+		lx, err := Lex([]byte(test.src), "")
+		require.NoError(t, err)
+		px, err := Parse(lx)
+		require.NoError(t, err)
+
+		ex := NewEval(lx)
+		ex.Limits = test.limits
+		require.NoError(t, ex.RegisterBuiltin("noop", func() {}))
+
+		// Limits are enforced per evaluation, such that an evaluator may evaluate any number of programs.
+
+		for i := 0; i < 2; i++ {
+			_, err = ex.Eval(px.Result)
+			if test.err == nil {
+				require.NoError(t, err, test.src)
+				continue
+			}
+			require.True(t, errors.Is(err, test.err), test.src)
+			require.EqualError(t, err, test.msg, test.src)
+		}
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	type key struct{}
+
+	lx, err := Lex([]byte("user = whoami; stop; never;"), "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "root"))
+	defer cancel()
+
+	called := false
+
+	ex := NewEval(lx)
+	require.NoError(t, ex.RegisterBuiltins(map[string]interface{}{
+		"whoami": func(ctx context.Context) string { return ctx.Value(key{}).(string) },
+		"stop":   func(ctx context.Context, p *Pipe) { cancel() },
+		"never":  func() { called = true },
+	}))
+
+	_, err = ex.EvalContext(ctx, px.Result)
+	require.True(t, errors.Is(err, context.Canceled))
+	require.EqualError(t, err, "(input):1:22: evaluation stopped: context canceled")
+	require.False(t, called)
+
+	user, _ := ex.Lookup("user")
+	require.Equal(t, "root", user)
+
+	// Evaluation stops once the deadline of a context is exceeded, even within pipelines run outside of a program.
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelExpired()
+
+	_, err = ex.RunContext(expired, Pipeline{{name: "never"}}, nil)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.False(t, called)
+
+	_, err = ex.Run(Pipeline{{name: "never"}}, nil)
+	require.NoError(t, err)
+	require.True(t, called)
+}
@@ -0,0 +1,161 @@
+package flatlang
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Load decodes the document data of the given format into a value. Documents are converted into the same values
+// that the evaluator produces: maps become map[string]interface{}, lists become []interface{}, and integers become
+// int64 values, or *big.Int values should they not fit into 64 bits.
+//
+// Values that have no flatlang representation, like nulls and datetimes, fail the load with an error that reports
+// the path to the value in the document.
+func Load(data []byte, format DataFormat) (interface{}, error) {
+	var (
+		doc interface{}
+		err error
+	)
+	switch format {
+	case JSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err = dec.Decode(&doc); err == nil {
+			if _, err := dec.Token(); err != io.EOF {
+				return nil, fmt.Errorf("failed to decode json: unexpected data after the top-level value")
+			}
+		}
+	case YAML:
+		err = yaml.Unmarshal(data, &doc)
+	case TOML:
+		var m map[string]interface{}
+		_, err = toml.Decode(string(data), &m)
+		doc = m
+	default:
+		return nil, fmt.Errorf("unknown data format %d", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", format, err)
+	}
+	return loadValue("$", doc)
+}
+
+// LoadFile decodes the document at path into a value. The format of the document is decided by the extension of
+// path, which is either .json, .yaml, .yml or .toml.
+func LoadFile(path string) (interface{}, error) {
+	var format DataFormat
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = JSON
+	case ".yaml", ".yml":
+		format = YAML
+	case ".toml":
+		format = TOML
+	default:
+		return nil, fmt.Errorf("%s: unable to tell the data format of the document from its extension", path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	val, err := Load(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return val, nil
+}
+
+// LoadBuiltins returns the builtin load, which loads the document at the path it is called with using LoadFile.
+// Relative paths are resolved against dir. As load may read any file readable by the program it is evaluated in, it
+// must be registered explicitly.
+func LoadBuiltins(dir string) map[string]interface{} {
+	return map[string]interface{}{
+		"load": func(path string) (interface{}, error) {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			return LoadFile(path)
+		},
+	}
+}
+
+// loadValue converts val, decoded from a document at path, into a flatlang value.
+func loadValue(path string, val interface{}) (interface{}, error) {
+	switch val := val.(type) {
+	case bool, string, int64, float64:
+		return val, nil
+	case int:
+		return int64(val), nil
+	case uint64:
+		if val > math.MaxInt64 {
+			return parseInt(strconv.FormatUint(val, 10))
+		}
+		return int64(val), nil
+	case json.Number:
+		var (
+			res interface{}
+			err error
+		)
+		if strings.ContainsAny(string(val), ".eE") {
+			res, err = strconv.ParseFloat(string(val), 64)
+		} else {
+			res, err = parseInt(string(val))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, 0, len(val))
+		for i, elem := range val {
+			elem, err := loadValue(fmt.Sprintf("%s[%d]", path, i), elem)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, elem)
+		}
+		return res, nil
+	case []map[string]interface{}:
+		res := make([]interface{}, 0, len(val))
+		for i, elem := range val {
+			elem, err := loadValue(fmt.Sprintf("%s[%d]", path, i), elem)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, elem)
+		}
+		return res, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for _, key := range sortedKeys(val) {
+			elem, err := loadValue(path+"."+key, val[key])
+			if err != nil {
+				return nil, err
+			}
+			res[key] = elem
+		}
+		return res, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for key, elem := range val {
+			skey, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: key %v is not a string", path, key)
+			}
+			m[skey] = elem
+		}
+		return loadValue(path, m)
+	case nil:
+		return nil, fmt.Errorf("%s: null has no flatlang representation", path)
+	}
+	return nil, fmt.Errorf("%s: %T has no flatlang representation", path, val)
+}
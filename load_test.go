@@ -0,0 +1,101 @@
+package flatlang
+
+import (
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	expected := map[string]interface{}{
+		"name":  "svc",
+		"port":  int64(8080),
+		"ratio": 0.5,
+		"debug": true,
+		"tags":  []interface{}{"a", "b"},
+		"db":    map[string]interface{}{"pool": map[string]interface{}{"max": int64(10)}},
+	}
+
+	for _, path := range []string{"testdata/load/config.json", "testdata/load/config.yaml", "testdata/load/config.toml"} {
+		val, err := LoadFile(path)
+		require.NoError(t, err, path)
+		require.Equal(t, expected, val, path)
+	}
+
+	val, err := Load([]byte(`{"big": 123456789012345678901234567890}`), JSON)
+	require.NoError(t, err)
+	expectedBig, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	require.Equal(t, map[string]interface{}{"big": expectedBig}, val)
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		src    string
+		format DataFormat
+		err    string
+	}{
+		{src: `{"a": [1, {"b": null}]}`, format: JSON, err: "$.a[1].b: null has no flatlang representation"},
+		{src: `{"a": 1} {}`, format: JSON, err: "failed to decode json: unexpected data after the top-level value"},
+		{src: "a:\n  1: x\n", format: YAML, err: "$.a: key 1 is not a string"},
+		{src: "a:\n  b: ~\n", format: YAML, err: "$.a.b: null has no flatlang representation"},
+		{src: "[[a]]\nb = 1\n[[a]]\nb = 1979-05-27T07:32:00Z\n", format: TOML, err: "$.a[1].b: time.Time has no flatlang representation"},
+	}
+
+	for _, test := range cases {
+		_, err := Load([]byte(test.src), test.format)
+		require.EqualError(t, err, test.err)
+	}
+
+	_, err := LoadFile("testdata/load/config.ini")
+	require.EqualError(t, err, "testdata/load/config.ini: unable to tell the data format of the document from its extension")
+}
+
+func TestLoadBuiltin(t *testing.T) {
+	lx, err := Lex([]byte("cfg = load 'config.toml';\nport = load 'config.json';"), "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	ex := NewEval(lx)
+	require.NoError(t, ex.RegisterBuiltins(LoadBuiltins("testdata/load")))
+	_, err = ex.Eval(px.Result)
+	require.NoError(t, err)
+
+	cfg, recorded := ex.Lookup("cfg")
+	require.True(t, recorded)
+	require.Equal(t, int64(8080), cfg.(map[string]interface{})["port"])
+
+	lx, err = Lex([]byte("missing = load 'missing.json';"), "")
+	require.NoError(t, err)
+	px, err = Parse(lx)
+	require.NoError(t, err)
+	ex.SetLexer(lx)
+	_, err = ex.Eval(px.Result)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `(input):1:11: failed to call method "load"`)
+}
+
+func TestLoadExported(t *testing.T) {
+	ex, _ := evalExport(t, `
+port = 8080;
+ratio = 1.0;
+name = 'true';
+quoted = "a \"quoted\"\tstring: #1";
+tags = ['a', 'yes', '1'];
+db = {host: 'localhost', pool: {max: 10, min: 1}, replicas: [{host: 'r1'}, [1, 2]]};
+`, nil)
+
+	var expected interface{}
+	for _, format := range []DataFormat{JSON, YAML, TOML} {
+		buf, err := ex.Export(ExportOptions{Format: format})
+		require.NoError(t, err)
+
+		val, err := Load(buf, format)
+		require.NoError(t, err, format)
+
+		if expected == nil {
+			expected = val
+		}
+		require.Equal(t, expected, val, format)
+	}
+}
@@ -1,5 +1,7 @@
 package flatlang
 
+import "fmt"
+
 type NodeType int
 
 const (
@@ -49,7 +51,12 @@ var NodeString = [...]string{
 	OpNode + '|':    "|",
 }
 
-func (t NodeType) String() string { return NodeString[t] }
+func (t NodeType) String() string {
+	if t < 0 || int(t) >= len(NodeString) || NodeString[t] == "" {
+		return fmt.Sprintf("NodeType(%d)", int(t))
+	}
+	return NodeString[t]
+}
 
 type Node struct {
 	Type   NodeType
@@ -74,6 +81,33 @@ func (n Node) Val(lx *Lexer) string {
 	return string(lx.Data[lx.Tokens[n.Tokens[0]].Pos:lx.Tokens[n.Tokens[0]].End])
 }
 
+// Span returns the offsets of the first byte of n, and of the byte immediately after n.
+func (n Node) Span(lx *Lexer) (pos, end int) {
+	pos, end = -1, -1
+	for _, i := range n.Tokens {
+		tok := lx.Tokens[i]
+		if pos == -1 || tok.Pos < pos {
+			pos = tok.Pos
+		}
+		if tok.End > end {
+			end = tok.End
+		}
+	}
+	for _, child := range n.Nodes {
+		cpos, cend := child.Span(lx)
+		if cpos == -1 {
+			continue
+		}
+		if pos == -1 || cpos < pos {
+			pos = cpos
+		}
+		if cend > end {
+			end = cend
+		}
+	}
+	return pos, end
+}
+
 func (n Node) Format(lx *Lexer) string {
 	buf := n.Type.String()
 	if buf == "" {
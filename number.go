@@ -0,0 +1,195 @@
+package flatlang
+
+import "fmt"
+
+// scanNumber scans the integer or floating-point literal starting at data[pos] following the syntax of Go numeric
+// literals. It returns the offset immediately after the literal, the symbol of the literal (int_ or float), and a
+// message describing why the literal is malformed should it be malformed.
+//
+// The implementation is adapted from go/scanner.
+func scanNumber(data []byte, pos int) (end, sym int, msg string) {
+	s := numberScanner{data: data, offset: pos}
+	sym = int_
+
+	base := 10        // number base
+	prefix := byte(0) // one of 0 (decimal), '0' (0-octal), 'x', 'o', or 'b'
+	digsep := 0       // bit 0: digit present, bit 1: '_' present
+	invalid := -1     // index of invalid digit in literal, or < 0
+
+	// integer part
+
+	if s.ch() != '.' {
+		if s.ch() == '0' {
+			s.offset++
+			switch lower(s.ch()) {
+			case 'x':
+				s.offset++
+				base, prefix = 16, 'x'
+			case 'o':
+				s.offset++
+				base, prefix = 8, 'o'
+			case 'b':
+				s.offset++
+				base, prefix = 2, 'b'
+			default:
+				base, prefix = 8, '0'
+				digsep = 1 // leading 0
+			}
+		}
+		digsep |= s.digits(base, &invalid)
+	}
+
+	// fractional part
+
+	if s.ch() == '.' {
+		sym = float
+		if prefix == 'o' || prefix == 'b' {
+			s.errorf("invalid radix point in %s", litname(prefix))
+		}
+		s.offset++
+		digsep |= s.digits(base, &invalid)
+	}
+
+	if digsep&1 == 0 {
+		s.errorf("%s has no digits", litname(prefix))
+	}
+
+	// exponent
+
+	if e := lower(s.ch()); e == 'e' || e == 'p' {
+		switch {
+		case e == 'e' && prefix != 0 && prefix != '0':
+			s.errorf("%q exponent requires decimal mantissa", s.ch())
+		case e == 'p' && prefix != 'x':
+			s.errorf("%q exponent requires hexadecimal mantissa", s.ch())
+		}
+		s.offset++
+		sym = float
+		if s.ch() == '+' || s.ch() == '-' {
+			s.offset++
+		}
+		ds := s.digits(10, nil)
+		digsep |= ds
+		if ds&1 == 0 {
+			s.errorf("exponent has no digits")
+		}
+	} else if prefix == 'x' && sym == float {
+		s.errorf("hexadecimal mantissa requires a 'p' exponent")
+	}
+
+	lit := data[pos:s.offset]
+
+	if sym == int_ && invalid >= 0 {
+		s.errorf("invalid digit %q in %s", data[invalid], litname(prefix))
+	}
+	if digsep&2 != 0 {
+		if i := invalidSep(lit); i >= 0 {
+			s.errorf("'_' must separate successive digits")
+		}
+	}
+
+	return s.offset, sym, s.msg
+}
+
+type numberScanner struct {
+	data   []byte
+	offset int
+	msg    string
+}
+
+func (s *numberScanner) ch() byte {
+	if s.offset < len(s.data) {
+		return s.data[s.offset]
+	}
+	return 0
+}
+
+func (s *numberScanner) errorf(format string, a ...interface{}) {
+	if s.msg == "" {
+		s.msg = fmt.Sprintf(format, a...)
+	}
+}
+
+func (s *numberScanner) digits(base int, invalid *int) (digsep int) {
+	if base <= 10 {
+		max := byte('0' + base)
+		for isDecimal(s.ch()) || s.ch() == '_' {
+			ds := 1
+			if s.ch() == '_' {
+				ds = 2
+			} else if s.ch() >= max && *invalid < 0 {
+				*invalid = s.offset // record invalid digit offset
+			}
+			digsep |= ds
+			s.offset++
+		}
+	} else {
+		for isHex(s.ch()) || s.ch() == '_' {
+			ds := 1
+			if s.ch() == '_' {
+				ds = 2
+			}
+			digsep |= ds
+			s.offset++
+		}
+	}
+	return
+}
+
+func litname(prefix byte) string {
+	switch prefix {
+	case 'x':
+		return "hexadecimal literal"
+	case 'o', '0':
+		return "octal literal"
+	case 'b':
+		return "binary literal"
+	}
+	return "decimal literal"
+}
+
+// invalidSep returns the index of the first invalid separator in x, or -1.
+func invalidSep(x []byte) int {
+	x1 := byte(' ') // prefix char, we only care if it's 'x'
+	d := byte('.')  // digit, one of '_', '0' (a digit), or '.' (anything else)
+	i := 0
+
+	// a prefix counts as a digit
+
+	if len(x) >= 2 && x[0] == '0' {
+		x1 = lower(x[1])
+		if x1 == 'x' || x1 == 'o' || x1 == 'b' {
+			d = '0'
+			i = 2
+		}
+	}
+
+	// mantissa and exponent
+
+	for ; i < len(x); i++ {
+		p := d // previous digit
+		d = x[i]
+		switch {
+		case d == '_':
+			if p != '0' {
+				return i
+			}
+		case isDecimal(d) || x1 == 'x' && isHex(d):
+			d = '0'
+		default:
+			if p == '_' {
+				return i - 1
+			}
+			d = '.'
+		}
+	}
+	if d == '_' {
+		return len(x) - 1
+	}
+
+	return -1
+}
+
+func lower(ch byte) byte     { return ('a' - 'A') | ch }
+func isDecimal(ch byte) bool { return '0' <= ch && ch <= '9' }
+func isHex(ch byte) bool     { return '0' <= ch && ch <= '9' || 'a' <= lower(ch) && lower(ch) <= 'f' }
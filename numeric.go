@@ -0,0 +1,320 @@
+package flatlang
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+var (
+	// ErrDivisionByZero is reported should ints or exact decimals be divided by zero, or should floats be divided by
+	// zero while NonFiniteError is in effect.
+	ErrDivisionByZero = errors.New("division by zero")
+
+	// ErrNonFinite is reported should arithmetic produce a float that is NaN or infinite while NonFiniteError is in
+	// effect.
+	ErrNonFinite = errors.New("result is not a finite float")
+)
+
+// NonFinitePolicy decides how floats that are NaN or infinite produced by arithmetic are treated.
+type NonFinitePolicy int
+
+const (
+	// NonFiniteError fails arithmetic that produces a float that is NaN or infinite.
+	NonFiniteError NonFinitePolicy = iota
+
+	// NonFiniteAllow keeps floats that are NaN or infinite as they are.
+	NonFiniteAllow
+)
+
+// Numbers are represented as int64 values, *big.Int values for integers that do not fit in an int64, float64 values,
+// or *big.Rat values for floats should exact decimals be opted into. Arithmetic between numbers of different kinds
+// promotes both operands to the kind ranked highest below.
+
+type numberKind int
+
+const (
+	notNumber numberKind = iota
+	intNumber
+	bigIntNumber
+	ratNumber
+	floatNumber
+)
+
+func kindOf(val interface{}) numberKind {
+	switch val.(type) {
+	case int64:
+		return intNumber
+	case *big.Int:
+		return bigIntNumber
+	case *big.Rat:
+		return ratNumber
+	case float64:
+		return floatNumber
+	}
+	return notNumber
+}
+
+func toBigInt(val interface{}) *big.Int {
+	switch val := val.(type) {
+	case int64:
+		return big.NewInt(val)
+	case *big.Int:
+		return val
+	}
+	return nil
+}
+
+func toRat(val interface{}) *big.Rat {
+	switch val := val.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(val)
+	case *big.Int:
+		return new(big.Rat).SetInt(val)
+	case *big.Rat:
+		return val
+	}
+	return nil
+}
+
+func toFloat(val interface{}) float64 {
+	switch val := val.(type) {
+	case int64:
+		return float64(val)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(val).Float64()
+		return f
+	case *big.Rat:
+		f, _ := val.Float64()
+		return f
+	case float64:
+		return val
+	}
+	return math.NaN()
+}
+
+// normalizeInt returns i as an int64 should it fit in an int64.
+func normalizeInt(i *big.Int) interface{} {
+	if i.IsInt64() {
+		return i.Int64()
+	}
+	return i
+}
+
+// parseInt parses the int literal s, falling back to a *big.Int should s not fit in an int64.
+func parseInt(s string) (interface{}, error) {
+	val, err := strconv.ParseInt(s, 0, 64)
+	if err == nil {
+		return val, nil
+	}
+	if num, ok := err.(*strconv.NumError); !ok || num.Err != strconv.ErrRange {
+		return nil, err
+	}
+	i, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, err
+	}
+	return i, nil
+}
+
+// parseRat parses the float literal s into a *big.Rat that exactly represents it.
+func parseRat(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, &strconv.NumError{Func: "parseRat", Num: s, Err: strconv.ErrSyntax}
+	}
+	return r, nil
+}
+
+// neg negates the number val. It reports false should val not be a number.
+func neg(val interface{}) (interface{}, bool) {
+	switch val := val.(type) {
+	case int64:
+		if val == math.MinInt64 {
+			return new(big.Int).Neg(big.NewInt(val)), true
+		}
+		return -val, true
+	case *big.Int:
+		return normalizeInt(new(big.Int).Neg(val)), true
+	case *big.Rat:
+		return new(big.Rat).Neg(val), true
+	case float64:
+		return -val, true
+	}
+	return nil, false
+}
+
+// isZero reports whether the number val is zero.
+func isZero(val interface{}) bool {
+	switch val := val.(type) {
+	case int64:
+		return val == 0
+	case *big.Int:
+		return val.Sign() == 0
+	case *big.Rat:
+		return val.Sign() == 0
+	case float64:
+		return val == 0
+	}
+	return false
+}
+
+// arith applies the arithmetic operator op (+, -, * or /) to the numbers lhs and rhs. It reports false should lhs or
+// rhs not be a number.
+func arith(op NodeType, lhs, rhs interface{}) (interface{}, bool) {
+	lk, rk := kindOf(lhs), kindOf(rhs)
+	if lk == notNumber || rk == notNumber {
+		return nil, false
+	}
+
+	kind := lk
+	if rk > kind {
+		kind = rk
+	}
+
+	switch kind {
+	case intNumber:
+		if res, ok := arithInt64(op, lhs.(int64), rhs.(int64)); ok {
+			return res, true
+		}
+		fallthrough
+	case bigIntNumber:
+		a, b := toBigInt(lhs), toBigInt(rhs)
+		res := new(big.Int)
+		switch op {
+		case OpNode + '+':
+			res.Add(a, b)
+		case OpNode + '-':
+			res.Sub(a, b)
+		case OpNode + '*':
+			res.Mul(a, b)
+		case OpNode + '/':
+			res.Quo(a, b)
+		}
+		return normalizeInt(res), true
+	case ratNumber:
+		a, b := toRat(lhs), toRat(rhs)
+		res := new(big.Rat)
+		switch op {
+		case OpNode + '+':
+			res.Add(a, b)
+		case OpNode + '-':
+			res.Sub(a, b)
+		case OpNode + '*':
+			res.Mul(a, b)
+		case OpNode + '/':
+			res.Quo(a, b)
+		}
+		return res, true
+	}
+
+	a, b := toFloat(lhs), toFloat(rhs)
+	switch op {
+	case OpNode + '+':
+		return a + b, true
+	case OpNode + '-':
+		return a - b, true
+	case OpNode + '*':
+		return a * b, true
+	}
+	return a / b, true
+}
+
+// arithInt64 applies the arithmetic operator op to a and b. It reports false should the result overflow an int64.
+func arithInt64(op NodeType, a, b int64) (int64, bool) {
+	switch op {
+	case OpNode + '+':
+		c := a + b
+		return c, (c > a) == (b > 0)
+	case OpNode + '-':
+		c := a - b
+		return c, (c < a) == (b > 0)
+	case OpNode + '*':
+		if a == 0 || b == 0 {
+			return 0, true
+		}
+		c := a * b
+		return c, c/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+	}
+	return a / b, !(a == math.MinInt64 && b == -1)
+}
+
+// compareNumbers compares the numbers a and b, returning -1, 0 or +1 should a be less than, equal to or greater than
+// b. It reports false should a or b not be a number, or be NaN, as NaN is not ordered with respect to any number.
+func compareNumbers(a, b interface{}) (int, bool) {
+	ak, bk := kindOf(a), kindOf(b)
+	if ak == notNumber || bk == notNumber {
+		return 0, false
+	}
+
+	kind := ak
+	if bk > kind {
+		kind = bk
+	}
+
+	switch kind {
+	case intNumber:
+		a, b := a.(int64), b.(int64)
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case bigIntNumber:
+		return toBigInt(a).Cmp(toBigInt(b)), true
+	case ratNumber:
+		return toRat(a).Cmp(toRat(b)), true
+	}
+
+	af, bf := toFloat(a), toFloat(b)
+	if math.IsNaN(af) || math.IsNaN(bf) {
+		return 0, false
+	}
+	switch {
+	case af < bf:
+		return -1, true
+	case af > bf:
+		return 1, true
+	}
+	return 0, true
+}
+
+// formatRat formats r as a decimal, exactly should r be representable as a finite decimal.
+func formatRat(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	// A fraction is representable as a finite decimal should its denominator only have the prime factors 2 and 5,
+	// with the number of decimal places being the greater of the multiplicity of either factor.
+
+	d := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	for d.Bit(0) == 0 {
+		d.Rsh(d, 1)
+		twos++
+	}
+	five, mod := big.NewInt(5), new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(d, five, mod)
+		if m.Sign() != 0 {
+			break
+		}
+		d = q
+		fives++
+	}
+
+	if d.Cmp(big.NewInt(1)) != 0 {
+		f, _ := r.Float64()
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	prec := twos
+	if fives > prec {
+		prec = fives
+	}
+	return r.FloatString(prec)
+}
@@ -34,13 +34,14 @@ Main : Program { p.Result = $1 };
 Program
 : { $$ = NewNode(ProgramNode) }
 | Program Var ';' { $1.N1($2).T1($3) }
-| Program Val ';' { $1.N($2.Nodes...).T1($3) }
+| Program Val ';' { $1.N1($2).T1($3) }
+| Program error ';' { Errflag = 0 }
 ;
 
 Ident: ident { $$ = NewNode(IdentNode, $1) };
 
 Var
-: Ident '=' Val { $$ = NewNode(VarNode, $2).N1($1).N($3.Nodes...) }
+: Ident '=' Val { $$ = NewNode(VarNode, $2).T($3.Tokens...).N1($1).N($3.Nodes...) }
 ;
 
 Val
@@ -57,7 +58,7 @@ Atom: Expr %prec pipe;
 
 Expr
 : Literal | Map | List
-| '(' Expr ')'  { $$ = $2 }
+| '(' Expr ')'  { $$ = NewNode(ExprNode, $1, $3).N1($2) }
 
 | Expr '/' Expr { $$ = NewOpNode('/', $2).N2($1, $3) }
 | Expr '*' Expr { $$ = NewOpNode('*', $2).N2($1, $3) }
@@ -1,7 +1,9 @@
 package flatlang
 
 import (
+	"errors"
 	"fmt"
+	"github.com/lithdew/flatlang/ast"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -15,3 +17,134 @@ func TestParse(t *testing.T) {
 
 	fmt.Println(px.Format())
 }
+
+func TestParseAST(t *testing.T) {
+	src := "db = 'sqlite://';\n" +
+		"paginate = default {offset: 0, limit: 1024} > require {offset: >=0, limit: (>=0 & <=1024)};\n" +
+		"get \"/posts\" > paginate > print `${db}` [1, -2.5, true];"
+
+	lx, err := Lex([]byte(src), "")
+	require.NoError(t, err)
+
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	text := func(n ast.Node) string { return src[n.Pos():n.End()] }
+
+	prog := px.AST
+	require.Len(t, prog.Stmts, 3)
+	require.Len(t, prog.Semicolons, 3)
+	require.Equal(t, ";", src[prog.Semicolons[1]:prog.Semicolons[1]+1])
+
+	db := prog.Stmts[0].(*ast.Assign)
+	require.Equal(t, "db", db.Name.Name)
+	require.Equal(t, "db = 'sqlite://'", text(db))
+	require.Equal(t, "=", src[db.Assign:db.Assign+1])
+
+	paginate := prog.Stmts[1].(*ast.Assign)
+	require.Len(t, paginate.Value.Calls, 2)
+	require.Len(t, paginate.Value.Pipes, 1)
+	require.Equal(t, ">", src[paginate.Value.Pipes[0]:paginate.Value.Pipes[0]+1])
+	require.Equal(t, "default {offset: 0, limit: 1024}", text(paginate.Value.Calls[0]))
+
+	require.Equal(t, "require", paginate.Value.Calls[1].Fun.(*ast.Ident).Name)
+	fields := paginate.Value.Calls[1].Args[0].(*ast.MapLit)
+	require.Len(t, fields.Fields, 2)
+	require.Len(t, fields.Commas, 1)
+	require.Equal(t, "limit: (>=0 & <=1024)", text(fields.Fields[1]))
+	require.Equal(t, ":", src[fields.Fields[1].Colon:fields.Fields[1].Colon+1])
+
+	limit := fields.Fields[1].Value.(*ast.ParenExpr).X.(*ast.BinaryExpr)
+	require.Equal(t, "&", limit.Op)
+	require.Equal(t, ">=0", text(limit.X))
+	require.Equal(t, ">=", limit.X.(*ast.UnaryExpr).Op)
+
+	get := prog.Stmts[2].(*ast.Pipeline)
+	require.Len(t, get.Calls, 3)
+	require.Equal(t, `get "/posts"`, text(get.Calls[0]))
+	require.Equal(t, "print `${db}` [1, -2.5, true]", text(get.Calls[2]))
+
+	str := get.Calls[2].Args[0].(*ast.StringLit)
+	require.EqualValues(t, '`', str.Quote)
+	require.Equal(t, "db", str.Parts[0].(*ast.Interp).X.(*ast.Ident).Name)
+
+	list := get.Calls[2].Args[1].(*ast.ListLit)
+	require.Len(t, list.Elems, 3)
+	require.Len(t, list.Commas, 2)
+	require.Equal(t, ast.Float, list.Elems[1].(*ast.UnaryExpr).X.(*ast.BasicLit).Kind)
+	require.Equal(t, "true", list.Elems[2].(*ast.BasicLit).Value)
+
+	// Every node must lie within the span of its parent. The children of a node are the nodes that Inspect visits
+	// directly beneath it.
+
+	children := func(n ast.Node) []ast.Node {
+		var res []ast.Node
+		ast.Inspect(n, func(child ast.Node) bool {
+			if child == n {
+				return true
+			}
+			res = append(res, child)
+			return false
+		})
+		return res
+	}
+
+	var within func(parent ast.Node)
+	within = func(parent ast.Node) {
+		require.True(t, parent.Pos() < parent.End(), text(parent))
+		for _, child := range children(parent) {
+			require.True(t, parent.Pos() <= child.Pos() && child.End() <= parent.End(), "%q lies outside of %q", text(child), text(parent))
+			within(child)
+		}
+	}
+	within(prog)
+}
+
+func TestParseErrors(t *testing.T) {
+	src := "a = ;\nb = 1;\nc = (1 +);\nd = {e 1};\nf = 2"
+
+	lx, err := Lex([]byte(src), "test.fbs")
+	require.NoError(t, err)
+
+	px, err := Parse(lx)
+	require.Error(t, err)
+
+	var diags Diagnostics
+	require.True(t, errors.As(err, &diags))
+	require.Len(t, diags, 4)
+
+	require.Equal(t, "test.fbs:1:5", diags[0].Pos.String())
+	require.Equal(t, "test.fbs:1:6", diags[0].End.String())
+	require.Equal(t, SeverityError, diags[0].Severity)
+	require.Equal(t, "syntax error: unexpected ';'", diags[0].Message)
+	require.Contains(t, diags[0].Expected, "identifier")
+	require.Contains(t, diags[0].Expected, "'{'")
+
+	require.Equal(t, "test.fbs:3:9", diags[1].Pos.String())
+	require.Equal(t, "syntax error: unexpected ')'", diags[1].Message)
+
+	require.Equal(t, "test.fbs:4:8", diags[2].Pos.String())
+	require.Equal(t, []string{"':'"}, diags[2].Expected)
+	require.Equal(t, "test.fbs:4:8: syntax error: unexpected int, expecting ':'", diags[2].Error())
+
+	require.Equal(t, "test.fbs:5:6", diags[3].Pos.String())
+	require.Equal(t, "syntax error: unexpected end of file", diags[3].Message)
+	require.Equal(t, []string{"';'"}, diags[3].Expected)
+
+	require.Equal(t, px.Diagnostics, diags)
+}
+
+func TestParseRecovery(t *testing.T) {
+	src := "a = ;\nb = 1;\nc = (1 +);\nd = 2;"
+
+	lx, err := Lex([]byte(src), "")
+	require.NoError(t, err)
+
+	px, err := Parse(lx)
+	require.Error(t, err)
+	require.Len(t, px.Diagnostics, 2)
+
+	require.Len(t, px.AST.Stmts, 2)
+	require.Equal(t, "b", px.AST.Stmts[0].(*ast.Assign).Name.Name)
+	require.Equal(t, "d", px.AST.Stmts[1].(*ast.Assign).Name.Name)
+}
@@ -1,34 +1,38 @@
 //go:generate ragel -Z -G2 lex.rl
 //go:generate goyacc parse.y
 //go:generate sed "/yyS :=/a\\\tp := yylex.(*Parser)" -i y.go
+//go:generate sed "/yylex.Error(yyErrorMessage/i\\\t\t\tp.state = yystate" -i y.go
 
 package flatlang
 
-import (
-	"fmt"
-	"strings"
-)
+import "github.com/lithdew/flatlang/ast"
 
 func init() {
 	yyErrorVerbose = true
 }
 
 type Parser struct {
-	lx     *Lexer
-	prev   int
-	last   int
-	errors []string
+	lx    *Lexer
+	prev  int
+	last  int
+	eof   bool
+	state int
 
-	Result *Node
+	Result      *Node
+	AST         *ast.Program
+	Diagnostics Diagnostics
 }
 
 func Parse(lx *Lexer) (*Parser, error) {
 	p := newParser(lx)
 	yyParse(p)
-	if len(p.errors) == 0 {
+	if p.Result != nil {
+		p.AST = newAST(lx, p.Result)
+	}
+	if len(p.Diagnostics) == 0 {
 		return p, nil
 	}
-	return p, fmt.Errorf("%s: %s", p.lx.file.Name(), strings.Join(p.errors, "; "))
+	return p, newSyntaxError(p.Diagnostics)
 }
 
 func newParser(lx *Lexer) *Parser {
@@ -37,6 +41,7 @@ func newParser(lx *Lexer) *Parser {
 
 func (p *Parser) Lex(val *yySymType) int {
 	if p.prev == p.last {
+		p.eof = true
 		return 0
 	}
 	p.prev++
@@ -44,8 +49,75 @@ func (p *Parser) Lex(val *yySymType) int {
 	return p.lx.Tokens[p.prev].Sym
 }
 
+// Error records a diagnostic of a syntax error at the token that was last lexed. Rather than reporting the message s
+// provided by the parser, the diagnostic lists all tokens that were expected in place of the token.
 func (p *Parser) Error(s string) {
-	p.errors = append(p.errors, p.lx.Tokens[p.prev].String()+": "+s)
+	pos, end, unexpected := len(p.lx.Data), len(p.lx.Data), tokenName(yyToknames[0])
+	if !p.eof {
+		tok := p.lx.Tokens[p.prev]
+		pos, end, unexpected = tok.Pos, tok.End, tokenName(Repr(tok.Sym))
+	}
+
+	p.Diagnostics = append(p.Diagnostics, Diagnostic{
+		Pos:      p.lx.Position(pos),
+		End:      p.lx.Position(end),
+		Severity: SeverityError,
+		Expected: expected(p.state),
+		Message:  "syntax error: unexpected " + unexpected,
+	})
+}
+
+// expected returns the names of the tokens that may be shifted or reduced in state. It is adapted from the
+// yyErrorMessage func generated by goyacc, without limiting the number of names returned.
+func expected(state int) []string {
+	const TOKSTART = 4
+
+	var names []string
+
+	base := yyPact[state]
+	for tok := TOKSTART; tok-1 < len(yyToknames); tok++ {
+		if n := base + tok; n >= 0 && n < yyLast && yyChk[yyAct[n]] == tok {
+			names = append(names, tokenName(yyToknames[tok-1]))
+		}
+	}
+
+	if yyDef[state] == -2 {
+		i := 0
+		for yyExca[i] != -1 || yyExca[i+1] != state {
+			i += 2
+		}
+		for i += 2; yyExca[i] >= 0; i += 2 {
+			tok := yyExca[i]
+			if tok < TOKSTART || yyExca[i+1] == 0 {
+				continue
+			}
+			names = append(names, tokenName(yyToknames[tok-1]))
+		}
+	}
+
+	return names
+}
+
+var tokenNames = map[string]string{
+	"$end":   "end of file",
+	"ident":  "identifier",
+	"bool_":  "bool",
+	"int_":   "int",
+	"interp": "'${'",
+	"negate": "'-'",
+	"lte":    "'<='",
+	"gte":    "'>='",
+}
+
+// tokenName returns a human-readable name of the token named name in the grammar.
+func tokenName(name string) string {
+	if tn, exists := tokenNames[name]; exists {
+		return tn
+	}
+	if len(name) == 1 {
+		return "'" + name + "'"
+	}
+	return name
 }
 
 func (p Parser) Format() string {
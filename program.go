@@ -0,0 +1,117 @@
+package flatlang
+
+import (
+	"context"
+	"fmt"
+)
+
+// Program is a compiled flatlang program. Programs are immutable, and may be run any number of times by any number
+// of goroutines at once. Each run evaluates the program with its own set of variables.
+type Program struct {
+	lx        *Lexer
+	root      *Node
+	decimals  bool
+	nonFinite NonFinitePolicy
+	limits    Limits
+	builtins  map[string]Builtin
+	consts    map[*Node]interface{}
+	orders    map[*Node][]int
+}
+
+// Compile compiles n, which is parsed from the tokens of lx, into a program without builtins.
+func Compile(lx *Lexer, n *Node) (*Program, error) {
+	return NewEval(lx).Compile(n)
+}
+
+// Compile compiles n into a program that is evaluated with the builtins registered to e, and with e.Decimals,
+// e.NonFinite and e.Limits. Builtins registered to e afterwards are not seen by the program.
+//
+// Literals are evaluated once while compiling, and the order that declarations are evaluated in is resolved once while
+// compiling, such that malformed literals and cyclic declarations are reported by Compile rather than by each run of
+// the program. Any error returned is an *EvalError.
+func (e *Evaluator) Compile(n *Node) (*Program, error) {
+	p := &Program{
+		lx:        e.lx,
+		root:      n,
+		decimals:  e.Decimals,
+		nonFinite: e.NonFinite,
+		limits:    e.Limits,
+		builtins:  make(map[string]Builtin, len(e.builtins)),
+		consts:    make(map[*Node]interface{}),
+		orders:    make(map[*Node][]int),
+	}
+	for name, b := range e.builtins {
+		p.builtins[name] = b
+	}
+
+	// Only immutable values are kept, as they are shared by every run of the program.
+
+	var compile func(n *Node) error
+	compile = func(n *Node) error {
+		if n.Type < 0 || int(n.Type) >= len(NodeString) || NodeString[n.Type] == "" {
+			return errorAt(e.lx, fmt.Errorf("unknown node type '%v'", n.Type), n)
+		}
+		switch n.Type {
+		case BoolNode, IntNode, FloatNode, TextNode:
+			val, err := e.Eval(n)
+			if err != nil {
+				return err
+			}
+			switch val.(type) {
+			case bool, int64, float64, string:
+				p.consts[n] = val
+			}
+		}
+		for _, child := range n.Nodes {
+			if err := compile(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := compile(n); err != nil {
+		return nil, err
+	}
+
+	if n.Type == ProgramNode {
+		order, err := e.order(n)
+		if err != nil {
+			return nil, err
+		}
+		p.orders[n] = order
+	}
+
+	return p, nil
+}
+
+// Run evaluates the program with its own set of variables, which are assigned vars beforehand. It returns the
+// evaluator holding the variables of the run, which may be used to look up variables and to run the pipelines that
+// they hold, along with the value of the program. Any error returned is an *EvalError.
+func (p *Program) Run(vars map[string]interface{}) (*Evaluator, interface{}, error) {
+	return p.RunContext(context.Background(), vars)
+}
+
+// RunContext runs the program the same way that Run does, with evaluation stopping and ctx being handed to builtins
+// the same way as with EvalContext.
+func (p *Program) RunContext(ctx context.Context, vars map[string]interface{}) (*Evaluator, interface{}, error) {
+	e := &Evaluator{
+		Decimals:  p.decimals,
+		NonFinite: p.nonFinite,
+		Limits:    p.limits,
+		lx:        p.lx,
+		sym:       make(map[string]interface{}, len(vars)),
+		builtins:  p.builtins,
+		shared:    true,
+		consts:    p.consts,
+		orders:    p.orders,
+	}
+	for name, val := range vars {
+		e.sym[name] = val
+	}
+
+	res, err := e.EvalContext(ctx, p.root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, res, nil
+}
@@ -0,0 +1,90 @@
+package flatlang
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	src := []byte(`
+paginate
+    = default {offset: 0, limit: 1024}
+    > clamp 'limit' 1024;
+
+limit = requested * 2;
+`)
+
+	lx, err := Lex(src, "")
+	require.NoError(t, err)
+	px, err := Parse(lx)
+	require.NoError(t, err)
+
+	ex := NewEval(lx)
+	require.NoError(t, ex.RegisterBuiltins(map[string]interface{}{
+		"default": func(p *Pipe, defaults map[string]interface{}) {
+			fields, _ := p.Value.(map[string]interface{})
+			res := make(map[string]interface{}, len(defaults)+len(fields))
+			for k, v := range defaults {
+				res[k] = v
+			}
+			for k, v := range fields {
+				res[k] = v
+			}
+			p.Value = res
+		},
+		"clamp": func(p *Pipe, key string, max int64) error {
+			fields := p.Value.(map[string]interface{})
+			if fields[key].(int64) > max {
+				return fmt.Errorf("%s exceeds %d", key, max)
+			}
+			return nil
+		},
+	}))
+
+	prog, err := ex.Compile(px.Result)
+	require.NoError(t, err)
+
+	// Builtins registered after compiling are not seen by the program.
+
+	require.NoError(t, ex.RegisterBuiltin("extra", func() {}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		i := int64(i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			run, res, err := prog.Run(map[string]interface{}{"requested": i})
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{nil, nil}, res)
+			require.NotContains(t, run.Builtins(), "extra")
+
+			limit, recorded := run.Lookup("limit")
+			require.True(t, recorded)
+			require.Equal(t, i*2, limit)
+
+			paginate, _ := run.Lookup("paginate")
+			out, err := run.Run(paginate.(Pipeline), map[string]interface{}{"limit": i})
+			require.NoError(t, err)
+			require.Equal(t, map[string]interface{}{"offset": int64(0), "limit": i}, out)
+
+			// Builtins registered to a run are not seen by other runs.
+
+			require.NoError(t, run.RegisterBuiltin(fmt.Sprintf("run%d", i), func() {}))
+			require.Len(t, run.Builtins(), 3)
+		}()
+	}
+	wg.Wait()
+
+	_, _, err = prog.Run(nil)
+	require.EqualError(t, err, "(input):6:9: unknown symbol 'requested'")
+}
+
+func TestCompileError(t *testing.T) {
+	_, err := Compile(nil, NewNode(ProgramNode).N1(&Node{Type: -1}))
+	require.EqualError(t, err, "unknown node type 'NodeType(-1)'")
+}
@@ -0,0 +1,35 @@
+package flatlang
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Std returns the standard set of builtins keyed by name. Builtins that print do so to w.
+func Std(w io.Writer) map[string]interface{} {
+	return map[string]interface{}{
+		"print": func(items ...interface{}) {
+			fmt.Fprintln(w, items...)
+		},
+		"printf": func(format string, items ...interface{}) {
+			fmt.Fprintf(w, format, items...)
+		},
+	}
+}
+
+// RegisterBuiltins registers each builtin in fns under the name it is keyed by.
+func (e *Evaluator) RegisterBuiltins(fns map[string]interface{}) error {
+	names := make([]string, 0, len(fns))
+	for name := range fns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := e.RegisterBuiltin(name, fns[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
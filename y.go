@@ -76,88 +76,95 @@ var yyExca = [...]int{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 2,
+	1, 1,
+	-2, 0,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 170
+const yyLast = 207
 
 var yyAct = [...]int{
 
-	9, 8, 32, 35, 36, 37, 5, 33, 34, 39,
-	38, 36, 37, 55, 42, 43, 44, 45, 46, 47,
-	48, 54, 41, 40, 39, 81, 36, 37, 6, 57,
-	53, 41, 40, 39, 38, 36, 37, 62, 63, 64,
-	65, 66, 67, 68, 41, 40, 39, 38, 36, 37,
-	31, 7, 4, 41, 40, 39, 38, 36, 37, 76,
-	77, 60, 34, 74, 52, 30, 59, 1, 49, 73,
-	32, 70, 58, 70, 80, 78, 79, 7, 25, 23,
-	24, 82, 72, 71, 69, 13, 61, 28, 56, 26,
-	50, 75, 20, 21, 22, 7, 27, 11, 29, 19,
-	12, 10, 51, 18, 16, 14, 15, 17, 7, 25,
-	23, 24, 3, 2, 0, 0, 13, 0, 28, 0,
-	26, 0, 0, 20, 21, 22, 0, 0, 0, 0,
-	19, 0, 0, 0, 18, 16, 14, 15, 17, 7,
-	25, 23, 24, 0, 0, 0, 0, 13, 0, 28,
-	0, 26, 0, 0, 20, 21, 22, 0, 0, 0,
-	0, 19, 0, 0, 0, 18, 0, 14, 15, 17,
+	10, 9, 32, 37, 33, 57, 6, 38, 39, 36,
+	4, 7, 35, 56, 34, 44, 45, 46, 47, 48,
+	49, 50, 33, 43, 42, 41, 83, 38, 39, 31,
+	59, 55, 43, 42, 41, 40, 38, 39, 70, 64,
+	65, 66, 67, 68, 69, 62, 63, 51, 43, 42,
+	41, 40, 38, 39, 43, 42, 41, 40, 38, 39,
+	72, 78, 79, 72, 36, 41, 40, 38, 39, 76,
+	52, 82, 73, 8, 71, 75, 77, 80, 81, 8,
+	26, 24, 25, 84, 8, 1, 54, 14, 74, 29,
+	58, 27, 61, 28, 21, 22, 23, 12, 60, 30,
+	13, 20, 11, 53, 3, 19, 17, 15, 16, 18,
+	5, 2, 0, 0, 8, 26, 24, 25, 0, 0,
+	0, 0, 14, 0, 29, 0, 27, 0, 0, 21,
+	22, 23, 0, 0, 0, 0, 20, 0, 0, 0,
+	19, 17, 15, 16, 18, 8, 26, 24, 25, 0,
+	0, 0, 0, 14, 0, 29, 0, 27, 0, 0,
+	21, 22, 23, 0, 0, 0, 0, 20, 0, 0,
+	0, 19, 17, 15, 16, 18, 8, 26, 24, 25,
+	0, 0, 0, 0, 14, 0, 29, 0, 27, 0,
+	0, 21, 22, 23, 0, 0, 0, 0, 20, 0,
+	0, 0, 19, 0, 15, 16, 18,
 }
 var yyPact = [...]int{
 
-	-1000, -1000, 102, 52, 37, -13, 133, -1000, -1000, -3,
-	-1000, -1000, -1000, 102, 102, 102, 102, 102, 102, 102,
-	-1000, -1000, -1000, -1000, -1000, -1000, 45, 2, 71, 55,
-	-1000, -1000, 102, 102, -1000, -1000, 102, 102, 102, 102,
-	102, 102, 28, -1000, -1000, -1000, -1000, -1000, -1000, 63,
-	61, 59, -1000, 79, -1000, 89, -1000, 19, -1000, 102,
-	133, -31, -1000, -1000, -25, -25, -18, -18, -1000, -1000,
-	-1000, -1000, -1000, -1000, 102, 102, 62, 19, 6, 19,
-	102, -1000, 19,
+	-1000, -1000, 108, 16, -11, 1, -8, 170, -1000, -1000,
+	-2, -1000, -1000, -1000, 139, 139, 139, 139, 139, 139,
+	139, -1000, -1000, -1000, -1000, -1000, -1000, 67, -6, 73,
+	81, -1000, -1000, 139, -1000, 139, -1000, -1000, 139, 139,
+	139, 139, 139, 139, 23, -1000, -1000, -1000, -1000, -1000,
+	-1000, 53, 50, 65, -1000, 64, -1000, 78, -1000, 29,
+	-1000, 139, 170, -29, -1000, -1000, -22, -22, 38, 38,
+	-1000, -1000, -1000, -1000, -1000, -1000, 139, 139, 59, 29,
+	7, 29, 139, -1000, 29,
 }
 var yyPgo = [...]int{
 
-	0, 113, 112, 52, 28, 1, 3, 0, 68, 102,
-	101, 100, 98, 97, 96, 67,
+	0, 111, 104, 10, 11, 1, 3, 0, 47, 103,
+	102, 100, 99, 97, 93, 85,
 }
 var yyR1 = [...]int{
 
-	0, 15, 1, 1, 1, 6, 2, 3, 3, 4,
-	4, 5, 7, 7, 7, 7, 7, 7, 7, 7,
-	7, 7, 7, 7, 7, 7, 7, 7, 10, 10,
-	10, 10, 10, 10, 10, 8, 8, 9, 9, 9,
-	11, 11, 12, 12, 13, 13, 14, 14,
+	0, 15, 1, 1, 1, 1, 6, 2, 3, 3,
+	4, 4, 5, 7, 7, 7, 7, 7, 7, 7,
+	7, 7, 7, 7, 7, 7, 7, 7, 7, 10,
+	10, 10, 10, 10, 10, 10, 8, 8, 9, 9,
+	9, 11, 11, 12, 12, 13, 13, 14, 14,
 }
 var yyR2 = [...]int{
 
-	0, 1, 0, 3, 3, 1, 3, 1, 3, 1,
-	2, 1, 1, 1, 1, 3, 3, 3, 3, 3,
-	3, 3, 2, 2, 2, 2, 2, 2, 1, 3,
-	3, 3, 1, 1, 1, 0, 2, 0, 2, 4,
-	2, 2, 2, 3, 2, 2, 4, 5,
+	0, 1, 0, 3, 3, 3, 1, 3, 1, 3,
+	1, 2, 1, 1, 1, 1, 3, 3, 3, 3,
+	3, 3, 3, 2, 2, 2, 2, 2, 2, 1,
+	3, 3, 3, 1, 1, 1, 0, 2, 0, 2,
+	4, 2, 2, 2, 3, 2, 2, 4, 5,
 }
 var yyChk = [...]int{
 
-	-1000, -15, -1, -2, -3, -6, -4, 6, -5, -7,
-	-10, -13, -11, 14, 34, 35, 33, 36, 32, 28,
-	21, 22, 23, 8, 9, 7, 18, -14, 16, -12,
-	13, 13, 33, 20, -5, -6, 29, 30, 28, 27,
-	26, 25, -7, -7, -7, -7, -7, -7, -7, -8,
-	-8, -9, 19, -6, 19, 11, 17, -7, 17, 11,
-	-4, -3, -7, -7, -7, -7, -7, -7, 15, 21,
-	10, 22, 23, 10, 4, 12, -6, -7, -7, -7,
-	12, 19, -7,
+	-1000, -15, -1, -2, -3, 2, -6, -4, 6, -5,
+	-7, -10, -13, -11, 14, 34, 35, 33, 36, 32,
+	28, 21, 22, 23, 8, 9, 7, 18, -14, 16,
+	-12, 13, 13, 33, 13, 20, -5, -6, 29, 30,
+	28, 27, 26, 25, -7, -7, -7, -7, -7, -7,
+	-7, -8, -8, -9, 19, -6, 19, 11, 17, -7,
+	17, 11, -4, -3, -7, -7, -7, -7, -7, -7,
+	15, 21, 10, 22, 23, 10, 4, 12, -6, -7,
+	-7, -7, 12, 19, -7,
 }
 var yyDef = [...]int{
 
-	2, -2, 1, 0, 0, 28, 7, 5, 9, 11,
-	12, 13, 14, 0, 0, 0, 0, 0, 0, 0,
-	35, 35, 37, 32, 33, 34, 0, 0, 0, 0,
-	3, 4, 0, 0, 10, 28, 0, 0, 0, 0,
-	0, 0, 0, 22, 23, 24, 25, 26, 27, 0,
-	0, 0, 44, 0, 45, 0, 40, 42, 41, 0,
-	8, 6, 16, 17, 18, 19, 20, 21, 15, 29,
-	36, 30, 31, 38, 0, 0, 0, 43, 0, 46,
-	0, 39, 47,
+	2, -2, -2, 0, 0, 0, 29, 8, 6, 10,
+	12, 13, 14, 15, 0, 0, 0, 0, 0, 0,
+	0, 36, 36, 38, 33, 34, 35, 0, 0, 0,
+	0, 3, 4, 0, 5, 0, 11, 29, 0, 0,
+	0, 0, 0, 0, 0, 23, 24, 25, 26, 27,
+	28, 0, 0, 0, 45, 0, 46, 0, 41, 43,
+	42, 0, 9, 7, 17, 18, 19, 20, 21, 22,
+	16, 30, 37, 31, 32, 39, 0, 0, 0, 44,
+	0, 47, 0, 40, 48,
 }
 var yyTok1 = [...]int{
 
@@ -444,6 +451,7 @@ yydefault:
 		/* error ... attempt to resume parsing */
 		switch Errflag {
 		case 0: /* brand new error */
+			p.state = yystate
 			yylex.Error(yyErrorMessage(yystate, yytoken))
 			Nerrs++
 			if yyDebug >= 1 {
@@ -544,127 +552,127 @@ yydefault:
 		yyDollar = yyS[yypt-3 : yypt+1]
 //line parse.y:37
 		{
-			yyDollar[1].node.N(yyDollar[2].node.Nodes...).T1(yyDollar[3].token)
+			yyDollar[1].node.N1(yyDollar[2].node).T1(yyDollar[3].token)
 		}
 	case 5:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parse.y:38
+		{
+			Errflag = 0
+		}
+	case 6:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parse.y:40
+//line parse.y:41
 		{
 			yyVAL.node = NewNode(IdentNode, yyDollar[1].token)
 		}
-	case 6:
+	case 7:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parse.y:43
+//line parse.y:44
 		{
-			yyVAL.node = NewNode(VarNode, yyDollar[2].token).N1(yyDollar[1].node).N(yyDollar[3].node.Nodes...)
+			yyVAL.node = NewNode(VarNode, yyDollar[2].token).T(yyDollar[3].node.Tokens...).N1(yyDollar[1].node).N(yyDollar[3].node.Nodes...)
 		}
-	case 7:
+	case 8:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parse.y:47
+//line parse.y:48
 		{
 			yyVAL.node = NewNode(ValNode).N1(yyDollar[1].node)
 		}
-	case 8:
+	case 9:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parse.y:48
+//line parse.y:49
 		{
 			yyDollar[1].node.T1(yyDollar[2].token).N1(yyDollar[3].node)
 		}
-	case 9:
+	case 10:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parse.y:52
+//line parse.y:53
 		{
 			yyVAL.node = NewNode(ValNode).N1(yyDollar[1].node)
 		}
-	case 10:
+	case 11:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:53
+//line parse.y:54
 		{
 			yyDollar[1].node.N1(yyDollar[2].node)
 		}
-	case 15:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parse.y:60
-		{
-			yyVAL.node = yyDollar[2].node
-		}
 	case 16:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parse.y:62
+//line parse.y:61
 		{
-			yyVAL.node = NewOpNode('/', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
+			yyVAL.node = NewNode(ExprNode, yyDollar[1].token, yyDollar[3].token).N1(yyDollar[2].node)
 		}
 	case 17:
 		yyDollar = yyS[yypt-3 : yypt+1]
 //line parse.y:63
 		{
-			yyVAL.node = NewOpNode('*', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
+			yyVAL.node = NewOpNode('/', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
 		}
 	case 18:
 		yyDollar = yyS[yypt-3 : yypt+1]
 //line parse.y:64
 		{
-			yyVAL.node = NewOpNode('-', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
+			yyVAL.node = NewOpNode('*', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
 		}
 	case 19:
 		yyDollar = yyS[yypt-3 : yypt+1]
 //line parse.y:65
 		{
-			yyVAL.node = NewOpNode('+', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
+			yyVAL.node = NewOpNode('-', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
 		}
 	case 20:
 		yyDollar = yyS[yypt-3 : yypt+1]
 //line parse.y:66
 		{
-			yyVAL.node = NewOpNode('&', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
+			yyVAL.node = NewOpNode('+', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
 		}
 	case 21:
 		yyDollar = yyS[yypt-3 : yypt+1]
 //line parse.y:67
 		{
-			yyVAL.node = NewOpNode('|', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
+			yyVAL.node = NewOpNode('&', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
 		}
 	case 22:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:69
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parse.y:68
 		{
-			yyVAL.node = NewOpNode('<', yyDollar[1].token).N1(yyDollar[2].node)
+			yyVAL.node = NewOpNode('|', yyDollar[2].token).N2(yyDollar[1].node, yyDollar[3].node)
 		}
 	case 23:
 		yyDollar = yyS[yypt-2 : yypt+1]
 //line parse.y:70
 		{
-			yyVAL.node = NewOpNode(lte, yyDollar[1].token).N1(yyDollar[2].node)
+			yyVAL.node = NewOpNode('<', yyDollar[1].token).N1(yyDollar[2].node)
 		}
 	case 24:
 		yyDollar = yyS[yypt-2 : yypt+1]
 //line parse.y:71
 		{
-			yyVAL.node = NewOpNode('>', yyDollar[1].token).N1(yyDollar[2].node)
+			yyVAL.node = NewOpNode(lte, yyDollar[1].token).N1(yyDollar[2].node)
 		}
 	case 25:
 		yyDollar = yyS[yypt-2 : yypt+1]
 //line parse.y:72
 		{
-			yyVAL.node = NewOpNode(gte, yyDollar[1].token).N1(yyDollar[2].node)
+			yyVAL.node = NewOpNode('>', yyDollar[1].token).N1(yyDollar[2].node)
 		}
 	case 26:
 		yyDollar = yyS[yypt-2 : yypt+1]
 //line parse.y:73
 		{
-			yyVAL.node = NewOpNode('!', yyDollar[1].token).N1(yyDollar[2].node)
+			yyVAL.node = NewOpNode(gte, yyDollar[1].token).N1(yyDollar[2].node)
 		}
 	case 27:
 		yyDollar = yyS[yypt-2 : yypt+1]
 //line parse.y:74
 		{
-			yyVAL.node = NewOpNode(negate, yyDollar[1].token).N1(yyDollar[2].node)
+			yyVAL.node = NewOpNode('!', yyDollar[1].token).N1(yyDollar[2].node)
 		}
-	case 29:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parse.y:79
+	case 28:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parse.y:75
 		{
-			yyVAL.node = yyDollar[2].node.T2(yyDollar[1].token, yyDollar[3].token)
+			yyVAL.node = NewOpNode(negate, yyDollar[1].token).N1(yyDollar[2].node)
 		}
 	case 30:
 		yyDollar = yyS[yypt-3 : yypt+1]
@@ -679,98 +687,104 @@ yydefault:
 			yyVAL.node = yyDollar[2].node.T2(yyDollar[1].token, yyDollar[3].token)
 		}
 	case 32:
-		yyDollar = yyS[yypt-1 : yypt+1]
+		yyDollar = yyS[yypt-3 : yypt+1]
 //line parse.y:82
 		{
-			yyVAL.node = NewNode(IntNode, yyDollar[1].token)
+			yyVAL.node = yyDollar[2].node.T2(yyDollar[1].token, yyDollar[3].token)
 		}
 	case 33:
 		yyDollar = yyS[yypt-1 : yypt+1]
 //line parse.y:83
 		{
-			yyVAL.node = NewNode(FloatNode, yyDollar[1].token)
+			yyVAL.node = NewNode(IntNode, yyDollar[1].token)
 		}
 	case 34:
 		yyDollar = yyS[yypt-1 : yypt+1]
 //line parse.y:84
 		{
-			yyVAL.node = NewNode(BoolNode, yyDollar[1].token)
+			yyVAL.node = NewNode(FloatNode, yyDollar[1].token)
 		}
 	case 35:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parse.y:85
+		{
+			yyVAL.node = NewNode(BoolNode, yyDollar[1].token)
+		}
+	case 36:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line parse.y:88
+//line parse.y:89
 		{
 			yyVAL.node = NewNode(StringNode)
 		}
-	case 36:
+	case 37:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:89
+//line parse.y:90
 		{
 			yyVAL.node = yyDollar[1].node.N1(NewNode(TextNode, yyDollar[2].token))
 		}
-	case 37:
+	case 38:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line parse.y:93
+//line parse.y:94
 		{
 			yyVAL.node = NewNode(StringNode)
 		}
-	case 38:
+	case 39:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:94
+//line parse.y:95
 		{
 			yyVAL.node = yyDollar[1].node.N1(NewNode(TextNode, yyDollar[2].token))
 		}
-	case 39:
+	case 40:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parse.y:95
+//line parse.y:96
 		{
 			yyVAL.node = yyDollar[1].node.N1(NewNode(InterpNode, yyDollar[2].token, yyDollar[4].token).N1(yyDollar[3].node))
 		}
-	case 40:
+	case 41:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:99
+//line parse.y:100
 		{
 			yyVAL.node = NewNode(ListNode, yyDollar[1].token, yyDollar[2].token)
 		}
-	case 41:
+	case 42:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:100
+//line parse.y:101
 		{
 			yyVAL.node = yyDollar[1].node.T1(yyDollar[2].token)
 		}
-	case 42:
+	case 43:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:104
+//line parse.y:105
 		{
 			yyVAL.node = NewNode(ListNode, yyDollar[1].token).N1(yyDollar[2].node)
 		}
-	case 43:
+	case 44:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parse.y:105
+//line parse.y:106
 		{
 			yyDollar[1].node.T1(yyDollar[2].token).N1(yyDollar[3].node)
 		}
-	case 44:
+	case 45:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:109
+//line parse.y:110
 		{
 			yyVAL.node = NewNode(MapNode, yyDollar[1].token, yyDollar[2].token)
 		}
-	case 45:
+	case 46:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parse.y:110
+//line parse.y:111
 		{
 			yyVAL.node = yyDollar[1].node.T1(yyDollar[2].token)
 		}
-	case 46:
+	case 47:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parse.y:114
+//line parse.y:115
 		{
 			yyVAL.node = NewNode(MapNode, yyDollar[1].token).N1(yyDollar[2].node).T1(yyDollar[3].token).N1(yyDollar[4].node)
 		}
-	case 47:
+	case 48:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parse.y:115
+//line parse.y:116
 		{
 			yyDollar[1].node.T1(yyDollar[2].token).N1(yyDollar[3].node).T1(yyDollar[4].token).N1(yyDollar[5].node)
 		}